@@ -0,0 +1,243 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"slices"
+)
+
+// ErrBatchAborted - the request was cancelled or not sent because another request of a fail fast batch failed
+var ErrBatchAborted = errors.New("batch aborted")
+
+// DefaultBatchConcurrency - maximum requests of a batch in flight when none is provided
+const DefaultBatchConcurrency = 10
+
+// BatchMode - how a batch handles failed requests
+type BatchMode int
+
+const (
+	// BatchCollectAll - send every request, each error is reported in its result
+	BatchCollectAll BatchMode = iota
+	// BatchFailFast - after the first failed request, cancel the requests in flight and send no more
+	BatchFailFast
+)
+
+// Batch - configuration for DoBatch and DoBatchSeq
+type Batch struct {
+	// Maximum requests in flight, default is DefaultBatchConcurrency
+	Concurrency int
+	// Error handling, default is BatchCollectAll
+	Mode BatchMode
+}
+
+// BatchResult - outcome of a request of a batch
+type BatchResult struct {
+	// Position of the request in the batch
+	Index   int
+	Request *Request
+	// Response with the body read into memory, so the connection is released. Nil when no response was received
+	Response *http.Response
+	// Error of the request, including an *APIError for 4xx / 5xx responses
+	Err error
+}
+
+// DoBatch - send the requests with at most batch.Concurrency in flight and return a result per request, in the order of the requests.
+// Every request is sent with Do, sharing the client's retries, timeouts, cache and cancellation.
+// The client has no rate limiter, bound the load with Concurrency or TransportOptions.MaxConnsPerHost.
+// Returns the errors of the failed requests joined in order, excluding requests aborted by BatchFailFast,
+// or the context error when the context is done. Requests that were not sent have an ErrBatchAborted or context error.
+//
+// Example:
+//
+//	requests := []*fetch.Request{
+//		{Method: http.MethodGet, URL: "https://api.example.com/users/1"},
+//		{Method: http.MethodGet, URL: "https://api.example.com/users/2"},
+//	}
+//
+//	results, err := client.DoBatch(ctx, requests, fetch.Batch{Concurrency: 5})
+//	for _, result := range results {
+//		if result.Err != nil {
+//			// Handle error
+//			continue
+//		}
+//		fmt.Println(result.Index, result.Response.StatusCode)
+//	}
+func (a *Client) DoBatch(ctx context.Context, requests []*Request, batch Batch) ([]BatchResult, error) {
+	results := make([]BatchResult, 0, len(requests))
+	for result := range a.batchSeq(ctx, slices.Values(requests), batch, false) {
+		results = append(results, result)
+	}
+
+	// requests not sent after a fail fast failure or the end of the context
+	if len(results) < len(requests) {
+		notSent := ctx.Err()
+		if notSent == nil {
+			notSent = abortCause(results)
+		}
+		for i := len(results); i < len(requests); i++ {
+			results = append(results, BatchResult{Index: i, Request: requests[i], Err: notSent})
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil && !errors.Is(result.Err, ErrBatchAborted) {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// DoBatchSeq - send the requests with at most batch.Concurrency in flight and yield the results in the order of the requests.
+// Requests are pulled from the sequence as slots free up, so the sequence may be long or generated on demand.
+// Results completed ahead of an earlier request are held until it completes and keep their slot,
+// so a slow request delays new requests rather than buffering an unbounded number of responses.
+// With BatchFailFast, iteration stops after the first failed result. Stopping the iteration cancels the requests in flight.
+//
+// Example:
+//
+//	for result := range client.DoBatchSeq(ctx, requests, fetch.Batch{Concurrency: 20, Mode: fetch.BatchFailFast}) {
+//		if result.Err != nil {
+//			// Handle error
+//			break
+//		}
+//		fmt.Println(result.Index, result.Response.StatusCode)
+//	}
+func (a *Client) DoBatchSeq(ctx context.Context, requests iter.Seq[*Request], batch Batch) iter.Seq[BatchResult] {
+	return a.batchSeq(ctx, requests, batch, batch.Mode == BatchFailFast)
+}
+
+// batchSeq - yield the results in order, optionally stopping after the first failed result
+func (a *Client) batchSeq(ctx context.Context, requests iter.Seq[*Request], batch Batch, stopOnError bool) iter.Seq[BatchResult] {
+	return func(yield func(BatchResult) bool) {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		concurrency := batch.Concurrency
+		if concurrency <= 0 {
+			concurrency = DefaultBatchConcurrency
+		}
+
+		next, stop := iter.Pull(requests)
+		defer stop()
+
+		slots := make(chan struct{}, concurrency)
+		// result of each request in flight or waiting for its turn, in order
+		var pending []chan BatchResult
+		// wait for the requests in flight before returning
+		defer func() {
+			cancel(nil)
+			for _, result := range pending {
+				<-result
+			}
+		}()
+
+		exhausted := false
+		index := 0
+		for {
+			var acquire chan<- struct{}
+			if !exhausted && ctx.Err() == nil {
+				acquire = slots
+			}
+
+			var head <-chan BatchResult
+			if len(pending) > 0 {
+				head = pending[0]
+			}
+
+			if acquire == nil && head == nil {
+				return
+			}
+
+			select {
+			case acquire <- struct{}{}:
+				req, ok := next()
+				if !ok {
+					<-slots
+					exhausted = true
+					continue
+				}
+
+				result := make(chan BatchResult, 1)
+				pending = append(pending, result)
+				go func(index int, req *Request) {
+					result <- a.doBatchRequest(ctx, cancel, batch.Mode, index, req)
+				}(index, req)
+				index++
+			case result := <-head:
+				// the slot is held until the result is yielded, bounding the results held in memory
+				pending = pending[1:]
+				<-slots
+				if !yield(result) {
+					return
+				}
+				if stopOnError && result.Err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// doBatchRequest - send the request and read the response body into memory
+func (a *Client) doBatchRequest(ctx context.Context, cancel context.CancelCauseFunc, mode BatchMode, index int, req *Request) BatchResult {
+	result := BatchResult{Index: index, Request: req}
+
+	resp, err := a.Do(ctx, req)
+	if resp != nil && resp.Body != nil {
+		body, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err == nil {
+			err = readErr
+		}
+	}
+	if resp != nil && resp.StatusCode != 0 {
+		result.Response = resp
+	}
+
+	if err != nil && mode == BatchFailFast {
+		cause := context.Cause(ctx)
+		if errors.Is(cause, ErrBatchAborted) && (errors.Is(err, context.Canceled) || errors.Is(err, ErrBatchAborted)) {
+			// cancelled by the failure of another request
+			err = cause
+		} else {
+			cancel(batchAborted(index, err))
+		}
+	}
+	result.Err = err
+
+	return result
+}
+
+// batchAborted - error of the requests cancelled or not sent after the request failed
+func batchAborted(index int, err error) error {
+	return fmt.Errorf("%w: request %d failed: %w", ErrBatchAborted, index, err)
+}
+
+// abortCause - error of the requests not sent after the first failure of a fail fast batch
+func abortCause(results []BatchResult) error {
+	for _, result := range results {
+		if errors.Is(result.Err, ErrBatchAborted) {
+			return result.Err
+		}
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			return batchAborted(result.Index, result.Err)
+		}
+	}
+
+	return ErrBatchAborted
+}
@@ -0,0 +1,233 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// batchRequests - GET requests for /0 to /n-1
+func batchRequests(url string, n int) []*Request {
+	requests := make([]*Request, 0, n)
+	for i := range n {
+		requests = append(requests, &Request{Method: http.MethodGet, URL: fmt.Sprintf("%s/%d", url, i)})
+	}
+
+	return requests
+}
+
+func TestClient_batch(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(&Options{})
+	})
+
+	err := group.
+		Test("results should keep the request order with bounded concurrency", func(t *testing.T) {
+			var inFlight, maxInFlight atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					peak := maxInFlight.Load()
+					if current <= peak || maxInFlight.CompareAndSwap(peak, current) {
+						break
+					}
+				}
+
+				// later requests complete first
+				index, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+				time.Sleep(time.Duration(20-index) * time.Millisecond)
+				_, _ = w.Write([]byte(r.URL.Path))
+			}))
+			t.Cleanup(srv.Close)
+
+			results, err := client.DoBatch(context.Background(), batchRequests(srv.URL, 20), Batch{Concurrency: 4})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 20, len(results))
+			for i, result := range results {
+				odize.AssertEqual(t, i, result.Index)
+				odize.AssertNoError(t, result.Err)
+				odize.AssertEqual(t, "/"+strconv.Itoa(i), readBody(t, result.Response))
+			}
+			odize.AssertTrue(t, maxInFlight.Load() <= 4)
+			odize.AssertTrue(t, maxInFlight.Load() > 1)
+		}).
+		Test("a slow first request should not let later responses pile up", func(t *testing.T) {
+			release := make(chan struct{})
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				if r.URL.Path == "/0" {
+					<-release
+				}
+				_, _ = w.Write([]byte(r.URL.Path))
+			})
+
+			var once sync.Once
+			t.Cleanup(func() { once.Do(func() { close(release) }) })
+
+			done := make(chan error)
+			go func() {
+				_, err := client.DoBatch(context.Background(), batchRequests(srv.URL, 10), Batch{Concurrency: 3})
+				done <- err
+			}()
+
+			time.Sleep(100 * time.Millisecond)
+			odize.AssertEqual(t, int32(3), count.Load())
+
+			once.Do(func() { close(release) })
+			odize.AssertNoError(t, <-done)
+			odize.AssertEqual(t, int32(10), count.Load())
+		}).
+		Test("collect all should send every request and join the errors", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				if r.URL.Path == "/1" || r.URL.Path == "/3" {
+					w.WriteHeader(http.StatusNotFound)
+				}
+				_, _ = w.Write([]byte(r.URL.Path))
+			})
+
+			results, err := client.DoBatch(context.Background(), batchRequests(srv.URL, 5), Batch{})
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, int32(5), count.Load())
+
+			odize.AssertNoError(t, results[0].Err)
+			odize.AssertTrue(t, errors.As(results[1].Err, &apiErr))
+			odize.AssertEqual(t, http.StatusNotFound, results[1].Response.StatusCode)
+			odize.AssertEqual(t, "/1", readBody(t, results[1].Response))
+			odize.AssertNoError(t, results[2].Err)
+			odize.AssertTrue(t, errors.As(results[3].Err, &apiErr))
+			odize.AssertNoError(t, results[4].Err)
+		}).
+		Test("fail fast should cancel the requests in flight and send no more", func(t *testing.T) {
+			var arrived atomic.Int32
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				arrived.Add(1)
+				if r.URL.Path == "/2" {
+					// fail once the whole first wave is in flight
+					deadline := time.Now().Add(time.Second)
+					for arrived.Load() < 3 && time.Now().Before(deadline) {
+						time.Sleep(time.Millisecond)
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			})
+
+			start := time.Now()
+			results, err := client.DoBatch(context.Background(), batchRequests(srv.URL, 10), Batch{Concurrency: 3, Mode: BatchFailFast})
+			odize.AssertTrue(t, time.Since(start) < 500*time.Millisecond)
+
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, http.StatusInternalServerError, apiErr.StatusCode)
+			odize.AssertFalse(t, errors.Is(err, ErrBatchAborted))
+			odize.AssertEqual(t, int32(3), count.Load())
+
+			odize.AssertEqual(t, 10, len(results))
+			for i, result := range results {
+				odize.AssertEqual(t, i, result.Index)
+				if i == 2 {
+					odize.AssertTrue(t, errors.As(result.Err, &apiErr))
+					odize.AssertFalse(t, errors.Is(result.Err, ErrBatchAborted))
+					continue
+				}
+				odize.AssertTrue(t, errors.Is(result.Err, ErrBatchAborted))
+				odize.AssertTrue(t, strings.Contains(result.Err.Error(), "request 2 failed"))
+			}
+		}).
+		Test("requests should share the client's retries", func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1)%2 == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			client = New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond})))
+			results, err := client.DoBatch(context.Background(), batchRequests(srv.URL, 6), Batch{Concurrency: 1})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 6, len(results))
+			odize.AssertEqual(t, int32(12), attempts.Load())
+		}).
+		Test("a cancelled context should stop the batch", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			results, err := client.DoBatch(ctx, batchRequests(srv.URL, 3), Batch{})
+			odize.AssertTrue(t, errors.Is(err, context.Canceled))
+			odize.AssertEqual(t, 3, len(results))
+			for _, result := range results {
+				odize.AssertTrue(t, errors.Is(result.Err, context.Canceled))
+			}
+			odize.AssertEqual(t, int32(0), count.Load())
+		}).
+		Test("sequences should be pulled on demand and stop with the iteration", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				_, _ = w.Write([]byte(r.URL.Path))
+			})
+
+			var pulled atomic.Int32
+			requests := func(yield func(*Request) bool) {
+				for i := 0; ; i++ {
+					pulled.Add(1)
+					if !yield(&Request{Method: http.MethodGet, URL: fmt.Sprintf("%s/%d", srv.URL, i)}) {
+						return
+					}
+				}
+			}
+
+			var paths []string
+			for result := range client.DoBatchSeq(context.Background(), requests, Batch{Concurrency: 2}) {
+				odize.AssertNoError(t, result.Err)
+				paths = append(paths, readBody(t, result.Response))
+				if len(paths) == 5 {
+					break
+				}
+			}
+
+			odize.AssertEqual(t, []string{"/0", "/1", "/2", "/3", "/4"}, paths)
+			odize.AssertTrue(t, pulled.Load() <= 8)
+		}).
+		Test("fail fast sequences should stop after the first failed result", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				if r.URL.Path == "/1" {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			})
+
+			var results []BatchResult
+			for result := range client.DoBatchSeq(context.Background(), slices.Values(batchRequests(srv.URL, 5)), Batch{Concurrency: 1, Mode: BatchFailFast}) {
+				results = append(results, result)
+			}
+
+			odize.AssertEqual(t, 2, len(results))
+			odize.AssertNoError(t, results[0].Err)
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(results[1].Err, &apiErr))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,569 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerCacheStatus = "Cache-Status"
+	// cacheStatusName - identifies this cache in the Cache-Status header (RFC 9211)
+	cacheStatusName = "fetch"
+	// maxHeuristicFreshness - upper bound of the freshness lifetime derived from Last-Modified
+	maxHeuristicFreshness = 24 * time.Hour
+)
+
+// CacheStatus - how the cache handled a response, see CacheStatusOf
+type CacheStatus string
+
+const (
+	// CacheNone - the response did not go through the cache
+	CacheNone CacheStatus = ""
+	// CacheHit - served from the cache without contacting the server
+	CacheHit CacheStatus = "hit"
+	// CacheMiss - forwarded to the server, the response may have been stored
+	CacheMiss CacheStatus = "miss"
+	// CacheRevalidated - the stored response was revalidated with a conditional request (304 Not Modified)
+	CacheRevalidated CacheStatus = "revalidated"
+	// CacheStale - a stale response was served, by stale-while-revalidate or stale-if-error
+	CacheStale CacheStatus = "stale"
+	// CacheBypass - the request was not eligible for caching, e.g. Cache-Control: no-store
+	CacheBypass CacheStatus = "bypass"
+)
+
+// CachedResponse - a stored response
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// When the request that produced the response was sent
+	RequestTime time.Time
+	// When the response was received
+	ResponseTime time.Time
+	// Request header values selected by the response's Vary header
+	VaryHeaders map[string]string
+}
+
+// heuristicallyCacheable - non error status codes that may be stored without explicit freshness (RFC 9110 15.1)
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+}
+
+// WithCache - cache GET responses in the store, honouring Cache-Control, Expires, Vary and Age
+func WithCache(store CacheStore) FnOpts {
+	return func(o *Options) error {
+		o.Cache = store
+		return nil
+	}
+}
+
+// CacheStatusOf - returns how the cache handled the response
+//
+// Example:
+//
+//	resp, err := client.Get(url, nil)
+//	if fetch.CacheStatusOf(resp) == fetch.CacheHit {
+//		// served from the cache
+//	}
+func CacheStatusOf(resp *http.Response) CacheStatus {
+	if resp == nil {
+		return CacheNone
+	}
+
+	members := strings.Split(resp.Header.Get(headerCacheStatus), ",")
+	for i := len(members) - 1; i >= 0; i-- {
+		params := strings.Split(members[i], ";")
+		if strings.TrimSpace(params[0]) != cacheStatusName {
+			continue
+		}
+
+		values := map[string]string{}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			values[name] = value
+		}
+
+		return cacheStatusFromParams(values)
+	}
+
+	return CacheNone
+}
+
+// cacheStatusFromParams - map Cache-Status parameters to a CacheStatus
+func cacheStatusFromParams(values map[string]string) CacheStatus {
+	_, hit := values["hit"]
+
+	switch {
+	case values["detail"] == "stale":
+		return CacheStale
+	case values["fwd-status"] == "304":
+		return CacheRevalidated
+	case hit:
+		return CacheHit
+	case values["fwd"] == "bypass":
+		return CacheBypass
+	case values["fwd"] != "":
+		return CacheMiss
+	}
+
+	return CacheNone
+}
+
+// setCacheStatus - append this cache's member to the Cache-Status header
+func setCacheStatus(resp *http.Response, status CacheStatus) {
+	if resp == nil || status == CacheNone {
+		return
+	}
+
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+
+	var member string
+	switch status {
+	case CacheHit:
+		member = "hit"
+	case CacheStale:
+		member = "hit; detail=stale"
+	case CacheRevalidated:
+		member = "fwd=stale; fwd-status=304"
+	case CacheBypass:
+		member = "fwd=bypass"
+	default:
+		member = "fwd=miss"
+	}
+
+	value := cacheStatusName + "; " + member
+	if existing := resp.Header.Get(headerCacheStatus); existing != "" {
+		value = existing + ", " + value
+	}
+	resp.Header.Set(headerCacheStatus, value)
+}
+
+// doCached - serve GET requests from the cache, storing and revalidating responses as needed
+func (a *Client) doCached(ctx context.Context, r *Request) (*http.Response, error) {
+	if r.Method != http.MethodGet {
+		resp, err := a.send(ctx, r)
+		if err == nil && isUnsafeMethod(r.Method) {
+			a.Cache.Delete(cacheKey(r))
+		}
+		return resp, err
+	}
+
+	reqHeader := requestHeader(r, a.DefaultHeaders)
+	reqCC := parseCacheControl(reqHeader)
+
+	if reqCC.has("no-store") {
+		resp, err := a.send(ctx, r)
+		setCacheStatus(resp, CacheBypass)
+		return resp, err
+	}
+
+	key := cacheKey(r)
+	entry, ok := a.Cache.Get(key)
+	if ok && !entry.matchesVary(reqHeader) {
+		ok = false
+	}
+
+	if !ok {
+		if reqCC.has("only-if-cached") {
+			return gatewayTimeout()
+		}
+		return a.fetchAndStore(ctx, r, key, reqHeader)
+	}
+
+	now := time.Now()
+	age := entry.age(now)
+	lifetime := entry.freshnessLifetime()
+	respCC := parseCacheControl(entry.Header)
+
+	if entry.isFresh(reqCC, respCC, age, lifetime) {
+		return entry.response(age, CacheHit), nil
+	}
+
+	if reqCC.has("only-if-cached") {
+		return gatewayTimeout()
+	}
+
+	staleness := age - lifetime
+	if window, ok := respCC.seconds("stale-while-revalidate"); ok && staleness <= window && !respCC.has("must-revalidate") && !reqCC.has("no-cache") {
+		a.revalidateInBackground(ctx, r, key, entry, reqHeader, age)
+		return entry.response(age, CacheStale), nil
+	}
+
+	return a.revalidate(ctx, r, key, entry, reqHeader, age)
+}
+
+// fetchAndStore - forward the request and store the response if it is cacheable
+func (a *Client) fetchAndStore(ctx context.Context, r *Request, key string, reqHeader http.Header) (*http.Response, error) {
+	requestTime := time.Now()
+	resp, err := a.send(ctx, r)
+	if err != nil {
+		return resp, err
+	}
+
+	return store(a.Cache, key, resp, reqHeader, requestTime, time.Now(), CacheMiss)
+}
+
+// revalidate - send a conditional request for a stale entry
+func (a *Client) revalidate(ctx context.Context, r *Request, key string, entry *CachedResponse, reqHeader http.Header, age time.Duration) (*http.Response, error) {
+	conditional := *r
+	conditional.Headers = mergeHeaders(r.Headers, entry.conditionalHeaders())
+
+	requestTime := time.Now()
+	resp, err := a.send(ctx, &conditional)
+	if err != nil {
+		if entry.allowsStaleIfError(reqHeader, age, err) {
+			discard(resp)
+			return entry.response(age, CacheStale), nil
+		}
+		return resp, err
+	}
+
+	responseTime := time.Now()
+	if resp.StatusCode == http.StatusNotModified {
+		discard(resp)
+		updated := entry.revalidated(resp.Header, requestTime, responseTime)
+		a.Cache.Set(key, updated)
+		return updated.response(updated.age(responseTime), CacheRevalidated), nil
+	}
+
+	return store(a.Cache, key, resp, reqHeader, requestTime, responseTime, CacheMiss)
+}
+
+// revalidateInBackground - revalidate the entry without blocking the caller, once per key at a time
+func (a *Client) revalidateInBackground(ctx context.Context, r *Request, key string, entry *CachedResponse, reqHeader http.Header, age time.Duration) {
+	if _, loaded := a.revalidations.LoadOrStore(key, true); loaded {
+		return
+	}
+
+	go func() {
+		defer a.revalidations.Delete(key)
+
+		resp, err := a.revalidate(context.WithoutCancel(ctx), r, key, entry, reqHeader, age)
+		if err == nil {
+			discard(resp)
+		}
+	}()
+}
+
+// store - buffer the response body and store the response if it is cacheable
+func store(cache CacheStore, key string, resp *http.Response, reqHeader http.Header, requestTime time.Time, responseTime time.Time, status CacheStatus) (*http.Response, error) {
+	if !isStorable(resp, reqHeader) {
+		setCacheStatus(resp, status)
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := &CachedResponse{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		VaryHeaders:  varyHeaders(resp.Header, reqHeader),
+	}
+	cache.Set(key, entry)
+
+	setCacheStatus(resp, status)
+	return resp, nil
+}
+
+// isStorable - checks the response can be stored by a private cache
+func isStorable(resp *http.Response, reqHeader http.Header) bool {
+	if resp == nil || resp.Body == nil || !heuristicallyCacheable[resp.StatusCode] {
+		return false
+	}
+
+	if parseCacheControl(reqHeader).has("no-store") || parseCacheControl(resp.Header).has("no-store") {
+		return false
+	}
+
+	for _, vary := range resp.Header.Values("Vary") {
+		if strings.Contains(vary, "*") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isFresh - checks the entry can be served without contacting the server
+func (e *CachedResponse) isFresh(reqCC cacheControl, respCC cacheControl, age time.Duration, lifetime time.Duration) bool {
+	if reqCC.has("no-cache") || respCC.has("no-cache") {
+		return false
+	}
+
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+
+	if lifetime > age {
+		return true
+	}
+
+	if !reqCC.has("max-stale") || respCC.has("must-revalidate") {
+		return false
+	}
+
+	maxStale, ok := reqCC.seconds("max-stale")
+	return !ok || age-lifetime <= maxStale
+}
+
+// allowsStaleIfError - checks stale-if-error permits serving the entry after a failed revalidation
+func (e *CachedResponse) allowsStaleIfError(reqHeader http.Header, age time.Duration, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+
+	staleness := age - e.freshnessLifetime()
+	for _, cc := range []cacheControl{parseCacheControl(reqHeader), parseCacheControl(e.Header)} {
+		if window, ok := cc.seconds("stale-if-error"); ok && staleness <= window {
+			return true
+		}
+	}
+
+	return false
+}
+
+// age - current age of the entry (RFC 9111 4.2.3)
+func (e *CachedResponse) age(now time.Time) time.Duration {
+	apparentAge := max(0, e.ResponseTime.Sub(e.date()))
+
+	ageValue := time.Duration(0)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparentAge, correctedAge) + now.Sub(e.ResponseTime)
+}
+
+// freshnessLifetime - explicit or heuristic freshness lifetime (RFC 9111 4.2.1)
+func (e *CachedResponse) freshnessLifetime() time.Duration {
+	cc := parseCacheControl(e.Header)
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+
+	if expires := e.Header.Get("Expires"); expires != "" {
+		parsed, err := http.ParseTime(expires)
+		if err != nil {
+			return 0
+		}
+		return parsed.Sub(e.date())
+	}
+
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil {
+		return min(e.date().Sub(lastModified)/10, maxHeuristicFreshness)
+	}
+
+	return 0
+}
+
+// date - the Date header, or the response time when missing
+func (e *CachedResponse) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+
+	return e.ResponseTime
+}
+
+// matchesVary - checks the request selects this entry
+func (e *CachedResponse) matchesVary(reqHeader http.Header) bool {
+	for name, value := range e.VaryHeaders {
+		if reqHeader.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// conditionalHeaders - validators to revalidate the entry with
+func (e *CachedResponse) conditionalHeaders() map[string]string {
+	headers := map[string]string{}
+	if etag := e.Header.Get("ETag"); etag != "" {
+		headers["If-None-Match"] = etag
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		headers["If-Modified-Since"] = lastModified
+	}
+
+	return headers
+}
+
+// revalidated - returns a copy of the entry updated with the headers of a 304 response
+func (e *CachedResponse) revalidated(header http.Header, requestTime time.Time, responseTime time.Time) *CachedResponse {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	updated.Header.Del("Age")
+	updated.Header.Del("Date")
+
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", headerCacheStatus:
+			continue
+		}
+		updated.Header[name] = values
+	}
+
+	return &updated
+}
+
+// response - build a response from the entry
+func (e *CachedResponse) response(age time.Duration, status CacheStatus) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(age.Seconds())))
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	}
+	setCacheStatus(resp, status)
+
+	return resp
+}
+
+// size - approximate memory used by the entry
+func (e *CachedResponse) size() int64 {
+	size := int64(len(e.Body))
+	for name, values := range e.Header {
+		for _, value := range values {
+			size += int64(len(name) + len(value))
+		}
+	}
+
+	return size
+}
+
+// varyHeaders - capture the request header values selected by the Vary header
+func varyHeaders(respHeader http.Header, reqHeader http.Header) map[string]string {
+	selected := map[string]string{}
+	for _, vary := range respHeader.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name != "" {
+				selected[name] = reqHeader.Get(name)
+			}
+		}
+	}
+
+	return selected
+}
+
+// requestHeader - the headers that will be sent with the request
+func requestHeader(r *Request, defaultHeaders map[string]string) http.Header {
+	header := http.Header{}
+	for key, value := range mergeHeaders(r.Headers, defaultHeaders) {
+		header.Set(key, value)
+	}
+
+	return header
+}
+
+// cacheKey - primary cache key for the request
+func cacheKey(r *Request) string {
+	return http.MethodGet + " " + r.URL
+}
+
+// isUnsafeMethod - unsafe methods invalidate stored responses for the URL (RFC 9111 4.4)
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+
+	return true
+}
+
+// gatewayTimeout - response for only-if-cached requests that can not be served from the cache
+func gatewayTimeout() (*http.Response, error) {
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)),
+		StatusCode: http.StatusGatewayTimeout,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+	}
+	setCacheStatus(resp, CacheMiss)
+
+	return resp, &APIError{
+		StatusCode: http.StatusGatewayTimeout,
+		StatusText: http.StatusText(http.StatusGatewayTimeout),
+		Message:    "only-if-cached: no stored response",
+	}
+}
+
+// cacheControl - parsed Cache-Control directives
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	directives := cacheControl{}
+	for _, line := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+
+	return directives
+}
+
+func (c cacheControl) has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// seconds - the directive's delta-seconds value
+func (c cacheControl) seconds(name string) (time.Duration, bool) {
+	value, ok := c[name]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,194 @@
+package fetch
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultMemoryCacheSize - size used by NewMemoryCache when no size is provided, 64MB
+const DefaultMemoryCacheSize = 64 << 20
+
+// MemoryCache - in memory, size bounded, least recently used cache store
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CachedResponse
+	size  int64
+}
+
+var _ CacheStore = (*MemoryCache)(nil)
+
+// NewMemoryCache - returns an in memory cache holding up to maxBytes of responses, least recently used responses are evicted first.
+// Uses DefaultMemoryCacheSize when maxBytes <= 0.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMemoryCacheSize
+	}
+
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get - returns the stored response and marks it as recently used
+func (m *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	m.order.MoveToFront(element)
+	return itemOf(element).entry, true
+}
+
+// Set - store the response, evicting least recently used responses until it fits.
+// Responses larger than the cache are not stored.
+func (m *MemoryCache) Set(key string, entry *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+
+	size := entry.size()
+	if size > m.maxBytes {
+		return
+	}
+
+	for m.size+size > m.maxBytes {
+		m.remove(itemOf(m.order.Back()).key)
+	}
+
+	m.items[key] = m.order.PushFront(&memoryCacheItem{key: key, entry: entry, size: size})
+	m.size += size
+}
+
+// Delete - remove the stored response
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+}
+
+// Len - number of stored responses
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+// Size - bytes used by stored responses
+func (m *MemoryCache) Size() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.size
+}
+
+// remove - remove the key, callers must hold the lock
+func (m *MemoryCache) remove(key string) {
+	element, ok := m.items[key]
+	if !ok {
+		return
+	}
+
+	m.size -= itemOf(element).size
+	m.order.Remove(element)
+	delete(m.items, key)
+}
+
+// itemOf - the item held by a list element
+func itemOf(element *list.Element) *memoryCacheItem {
+	item, _ := element.Value.(*memoryCacheItem)
+	return item
+}
+
+// DiskCache - on disk cache store, each response is stored as a JSON file named by the hash of its key
+type DiskCache struct {
+	dir string
+}
+
+var _ CacheStore = (*DiskCache)(nil)
+
+// NewDiskCache - returns a cache store persisting responses in dir, creating it if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &DiskCache{dir: dir}, nil
+}
+
+// Get - returns the stored response, unreadable files are treated as a miss
+func (d *DiskCache) Get(key string) (*CachedResponse, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CachedResponse
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+// Set - store the response, the file is replaced atomically
+func (d *DiskCache) Set(key string, entry *CachedResponse) {
+	logPrefix := "fetch: DiskCache"
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("%s: encode error [%s]", logPrefix, err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		log.Printf("%s: write error [%s]", logPrefix, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path(key))
+	}
+	if err != nil {
+		log.Printf("%s: write error [%s]", logPrefix, err)
+	}
+}
+
+// Delete - remove the stored response
+func (d *DiskCache) Delete(key string) {
+	_ = os.Remove(d.path(key))
+}
+
+// path - file for the key
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,372 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// cacheTestServer - counts requests and responds with the handler
+func cacheTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, count int32)) (*httptest.Server, *atomic.Int32) {
+	var count atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, count.Add(1))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &count
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	data, err := io.ReadAll(resp.Body)
+	odize.AssertNoError(t, err)
+	odize.AssertNoError(t, resp.Body.Close())
+	return string(data)
+}
+
+// labelledCache - a cache store value that is not comparable
+type labelledCache struct {
+	*MemoryCache
+	labels map[string]string
+}
+
+func TestClient_cache(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithCache(NewMemoryCache(0))))
+	})
+
+	err := group.
+		Test("fresh response should be served from the cache", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertEqual(t, CacheMiss, CacheStatusOf(resp))
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertEqual(t, CacheHit, CacheStatusOf(resp))
+			odize.AssertEqual(t, "0", resp.Header.Get("Age"))
+			odize.AssertEqual(t, int32(1), count.Load())
+		}).
+		Test("stale response should be revalidated with the etag", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Cache-Control", "max-age=5")
+				w.Header().Set("Date", time.Now().Add(-10*time.Second).Format(http.TimeFormat))
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.StatusOK, resp.StatusCode)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertEqual(t, CacheRevalidated, CacheStatusOf(resp))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("expires in the past should not be served from the cache", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Expires", time.Now().Add(-time.Minute).Format(http.TimeFormat))
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			for range 2 {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+				odize.AssertEqual(t, CacheMiss, CacheStatusOf(resp))
+			}
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("expires in the future should be served from the cache", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Date", time.Now().Format(http.TimeFormat))
+				w.Header().Set("Expires", time.Now().Add(time.Minute).Format(http.TimeFormat))
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			for range 2 {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+			}
+			odize.AssertEqual(t, int32(1), count.Load())
+		}).
+		Test("age header should reduce freshness", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.Header().Set("Age", "120")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			for range 2 {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+			}
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("vary should select the stored response", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.Header().Set("Vary", "Accept-Language")
+				_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+			})
+
+			resp, err := client.Get(srv.URL, map[string]string{"Accept-Language": "en"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "en", readBody(t, resp))
+
+			resp, err = client.Get(srv.URL, map[string]string{"Accept-Language": "fr"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "fr", readBody(t, resp))
+			odize.AssertEqual(t, CacheMiss, CacheStatusOf(resp))
+
+			resp, err = client.Get(srv.URL, map[string]string{"Accept-Language": "fr"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "fr", readBody(t, resp))
+			odize.AssertEqual(t, CacheHit, CacheStatusOf(resp))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("no-store response should not be stored", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "no-store, max-age=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			for range 2 {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+			}
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("no-cache request should revalidate", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, map[string]string{"Cache-Control": "no-cache"})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("no-store request should bypass the cache", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, map[string]string{"Cache-Control": "no-store"})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, CacheBypass, CacheStatusOf(resp))
+
+			resp, err = client.Get(srv.URL, map[string]string{"Cache-Control": "only-if-cached"})
+			odize.AssertEqual(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+		}).
+		Test("stale-if-error should serve the stale response", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, count int32) {
+				if count > 1 {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Cache-Control", "max-age=0, stale-if-error=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertEqual(t, CacheStale, CacheStatusOf(resp))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("stale-while-revalidate should serve stale and revalidate in the background", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, count int32) {
+				w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+				if count > 1 {
+					_, _ = w.Write([]byte("updated"))
+					return
+				}
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertEqual(t, CacheStale, CacheStatusOf(resp))
+
+			stored := ""
+			deadline := time.Now().Add(time.Second)
+			for stored != "updated" && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+				if entry, ok := client.Cache.Get(http.MethodGet + " " + srv.URL); ok {
+					stored = string(entry.Body)
+				}
+			}
+			odize.AssertEqual(t, "updated", stored)
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("stale-while-revalidate should support stores that are not comparable", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			client = New(WithOpts(WithCache(labelledCache{MemoryCache: NewMemoryCache(0), labels: map[string]string{}})))
+			for range 2 {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				odize.AssertEqual(t, "hello", readBody(t, resp))
+			}
+
+			deadline := time.Now().Add(time.Second)
+			for count.Load() < 2 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("unsafe methods should invalidate the stored response", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Cache-Control", "max-age=60")
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Post(srv.URL, nil, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, CacheMiss, CacheStatusOf(resp))
+			odize.AssertEqual(t, int32(3), count.Load())
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestClient_cache_disk_store(t *testing.T) {
+	store, err := NewDiskCache(t.TempDir())
+	odize.AssertNoError(t, err)
+
+	srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	resp, err := New(WithOpts(WithCache(store))).Get(srv.URL, nil)
+	odize.AssertNoError(t, err)
+	readBody(t, resp)
+
+	resp, err = New(WithOpts(WithCache(store))).Get(srv.URL, nil)
+	odize.AssertNoError(t, err)
+	odize.AssertEqual(t, "hello", readBody(t, resp))
+	odize.AssertEqual(t, CacheHit, CacheStatusOf(resp))
+	odize.AssertEqual(t, int32(1), count.Load())
+}
+
+func TestMemoryCache_should_evict_least_recently_used(t *testing.T) {
+	cache := NewMemoryCache(10)
+
+	cache.Set("a", &CachedResponse{Body: []byte("aaaa")})
+	cache.Set("b", &CachedResponse{Body: []byte("bbbb")})
+	_, _ = cache.Get("a")
+	cache.Set("c", &CachedResponse{Body: []byte("cccc")})
+
+	_, ok := cache.Get("b")
+	odize.AssertFalse(t, ok)
+	_, ok = cache.Get("a")
+	odize.AssertTrue(t, ok)
+	_, ok = cache.Get("c")
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, int64(8), cache.Size())
+}
+
+func TestMemoryCache_should_not_store_oversized_entries(t *testing.T) {
+	cache := NewMemoryCache(2)
+
+	cache.Set("a", &CachedResponse{Body: []byte("aaaa")})
+	odize.AssertEqual(t, 0, cache.Len())
+}
+
+func TestDiskCache_delete(t *testing.T) {
+	store, err := NewDiskCache(t.TempDir())
+	odize.AssertNoError(t, err)
+
+	store.Set("a", &CachedResponse{StatusCode: http.StatusOK, Body: []byte("aaaa")})
+	entry, ok := store.Get("a")
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, "aaaa", string(entry.Body))
+
+	store.Delete("a")
+	_, ok = store.Get("a")
+	odize.AssertFalse(t, ok)
+}
+
+func TestCacheStatusOf(t *testing.T) {
+	tests := []struct {
+		header string
+		want   CacheStatus
+	}{
+		{header: "", want: CacheNone},
+		{header: "fetch; hit", want: CacheHit},
+		{header: "fetch; fwd=miss", want: CacheMiss},
+		{header: "fetch; fwd=stale; fwd-status=304", want: CacheRevalidated},
+		{header: "fetch; hit; detail=stale", want: CacheStale},
+		{header: "fetch; fwd=bypass", want: CacheBypass},
+		{header: "CDN; hit, fetch; fwd=miss", want: CacheMiss},
+		{header: "CDN; hit", want: CacheNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			resp.Header.Set("Cache-Status", tt.header)
+			odize.AssertEqual(t, tt.want, CacheStatusOf(resp))
+		})
+	}
+}
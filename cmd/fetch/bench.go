@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/code-gorilla-au/fetch"
+)
+
+// defaultBenchDuration - duration of a run without -duration or -n
+const defaultBenchDuration = 10 * time.Second
+
+// benchFlags - flags of the bench command
+type benchFlags struct {
+	clientFlags
+	method      string
+	data        string
+	rate        float64
+	concurrency int
+	duration    time.Duration
+	requests    int64
+	json        bool
+}
+
+// benchCommand - send requests at a target rate or concurrency and report latencies
+func benchCommand(ctx context.Context, env *environment, args []string) int {
+	var f benchFlags
+	flags := flag.NewFlagSet("fetch bench", flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(env.stderr, "Usage: fetch bench [flags] URL")
+		flags.PrintDefaults()
+		_, _ = fmt.Fprintln(env.stderr, "\nWithout -rate, -c workers send requests back to back (closed loop).")
+		_, _ = fmt.Fprintln(env.stderr, "With -rate, requests start at the rate regardless of responses (open loop), at most -c in flight.")
+	}
+
+	f.register(flags)
+	flags.StringVar(&f.method, "X", "", "request `method`, default is GET, or POST with -d")
+	flags.StringVar(&f.data, "d", "", "request body, @path reads a file and @- reads stdin")
+	flags.Float64Var(&f.rate, "rate", 0, "requests started per second, enables the open loop model")
+	flags.IntVar(&f.concurrency, "c", 10, "concurrent workers, or the maximum requests in flight with -rate")
+	flags.DurationVar(&f.duration, "duration", 0, "duration of the run, default is 10s unless -n is set")
+	flags.Int64Var(&f.requests, "n", 0, "maximum number of requests, default is unlimited")
+	flags.BoolVar(&f.json, "json", false, "print the report as JSON")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if len(positional) != 1 || f.concurrency < 1 || f.rate < 0 || f.duration < 0 || f.requests < 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if f.duration == 0 && f.requests == 0 {
+		f.duration = defaultBenchDuration
+	}
+
+	body, closeBody, err := openBody(env.stdin, f.data)
+	if err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitError
+	}
+	defer closeBody()
+
+	b, err := newBench(&f, positional[0], body)
+	if err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitError
+	}
+
+	report := b.run(ctx, &f)
+	if f.json {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(report); err != nil {
+			_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+			return exitError
+		}
+	} else {
+		report.print(env.stdout)
+	}
+
+	if report.Failed > 0 {
+		return exitError
+	}
+
+	return exitOK
+}
+
+// bench - a load test run
+type bench struct {
+	client   *fetch.Client
+	method   string
+	url      string
+	headers  map[string]string
+	body     []byte
+	attempts *attemptCounter
+	// maximum number of requests, 0 is unlimited
+	limit  int64
+	issued atomic.Int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    map[string]int
+	failed    int
+	bytes     int64
+	dropped   int
+}
+
+func newBench(f *benchFlags, url string, body io.Reader) (*bench, error) {
+	b := &bench{
+		url:      url,
+		headers:  f.headers,
+		limit:    f.requests,
+		statuses: map[int]int{},
+		errors:   map[string]int{},
+	}
+
+	if body != nil {
+		// buffer the body so every request sends it
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		b.body = content
+	}
+
+	b.method = strings.ToUpper(f.method)
+	if b.method == "" {
+		b.method = http.MethodGet
+		if b.body != nil {
+			b.method = http.MethodPost
+		}
+	}
+
+	transport := http.DefaultTransport
+	if base, ok := transport.(*http.Transport); ok {
+		tuned := base.Clone()
+		// keep a connection per worker instead of reconnecting
+		tuned.MaxIdleConns = f.concurrency
+		tuned.MaxIdleConnsPerHost = f.concurrency
+		transport = tuned
+	}
+	b.attempts = &attemptCounter{transport: transport}
+
+	b.client = fetch.New(fetch.WithOpts(append(f.options(), fetch.WithHTTPClient(&http.Client{Transport: b.attempts}))...))
+
+	return b, nil
+}
+
+// run - drive the client until the duration elapses, the request limit is reached or the context is cancelled
+func (b *bench) run(ctx context.Context, f *benchFlags) *benchReport {
+	stop := make(chan struct{})
+	if f.duration > 0 {
+		timer := time.AfterFunc(f.duration, func() { close(stop) })
+		defer timer.Stop()
+	}
+
+	start := time.Now()
+	if f.rate > 0 {
+		b.openLoop(ctx, stop, f.rate, f.concurrency)
+	} else {
+		b.closedLoop(ctx, stop, f.concurrency)
+	}
+
+	return b.report(f, time.Since(start))
+}
+
+// closedLoop - workers send the next request once the previous one completes
+func (b *bench) closedLoop(ctx context.Context, stop <-chan struct{}, concurrency int) {
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Go(func() {
+			for b.next(ctx, stop) {
+				b.send(ctx)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// openLoop - requests start at the rate regardless of responses, requests that would exceed the maximum in flight are dropped
+// and do not count against the request limit
+func (b *bench) openLoop(ctx context.Context, stop <-chan struct{}, rate float64, maxInFlight int) {
+	interval := time.Duration(float64(time.Second) / rate)
+	ticker := time.NewTicker(max(interval, time.Microsecond))
+	defer ticker.Stop()
+
+	inFlight := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for b.running(ctx, stop) && b.remaining() {
+		select {
+		case inFlight <- struct{}{}:
+			// the only place requests start in this model, so the reservation cannot exceed the limit
+			b.issued.Add(1)
+			wg.Go(func() {
+				defer func() { <-inFlight }()
+				b.send(ctx)
+			})
+		default:
+			b.mu.Lock()
+			b.dropped++
+			b.mu.Unlock()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// next - reserve the next request, false once the run is over
+func (b *bench) next(ctx context.Context, stop <-chan struct{}) bool {
+	return b.running(ctx, stop) && (b.limit == 0 || b.issued.Add(1) <= b.limit)
+}
+
+// running - false once the duration elapses or the context is cancelled
+func (b *bench) running(ctx context.Context, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-ctx.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// remaining - the request limit allows another request to start
+func (b *bench) remaining() bool {
+	return b.limit == 0 || b.issued.Load() < b.limit
+}
+
+// send - send a request and record its outcome
+func (b *bench) send(ctx context.Context) {
+	req := &fetch.Request{Method: b.method, URL: b.url, Headers: b.headers}
+	if b.body != nil {
+		req.Body = bytes.NewReader(b.body)
+	}
+
+	start := time.Now()
+	resp, err := b.client.Do(ctx, req)
+	var received int64
+	status := 0
+	if resp != nil && resp.Body != nil {
+		received, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		status = resp.StatusCode
+	}
+	latency := time.Since(start)
+
+	var apiErr *fetch.APIError
+	if err != nil && !errors.As(err, &apiErr) && ctx.Err() != nil {
+		// interrupted, not a result of the run
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.latencies = append(b.latencies, latency)
+	b.bytes += received
+	if status != 0 {
+		b.statuses[status]++
+	}
+	if err != nil {
+		b.failed++
+		if apiErr == nil {
+			b.errors[err.Error()]++
+		}
+	}
+}
+
+// attemptCounter - counts the attempts sent by the client, including retries
+type attemptCounter struct {
+	transport http.RoundTripper
+	count     atomic.Int64
+}
+
+func (a *attemptCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	a.count.Add(1)
+	return a.transport.RoundTrip(req)
+}
+
+// benchReport - outcome of a run
+type benchReport struct {
+	// open or closed loop
+	Model      string         `json:"model"`
+	Rate       float64        `json:"rate,omitempty"`
+	Workers    int            `json:"concurrency"`
+	Duration   time.Duration  `json:"-"`
+	DurationMS float64        `json:"duration_ms"`
+	Requests   int            `json:"requests"`
+	Throughput float64        `json:"throughput"`
+	Attempts   int64          `json:"attempts"`
+	Retries    int64          `json:"retries"`
+	Dropped    int            `json:"dropped"`
+	Failed     int            `json:"failed"`
+	Bytes      int64          `json:"bytes"`
+	Latency    latencyReport  `json:"latency_ms"`
+	Statuses   map[int]int    `json:"statuses"`
+	Errors     map[string]int `json:"errors,omitempty"`
+}
+
+// latencyReport - latency distribution in milliseconds
+type latencyReport struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+func (b *bench) report(f *benchFlags, duration time.Duration) *benchReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &benchReport{
+		Model:      "closed",
+		Workers:    f.concurrency,
+		Duration:   duration,
+		DurationMS: milliseconds(duration),
+		Requests:   len(b.latencies),
+		Attempts:   b.attempts.count.Load(),
+		Dropped:    b.dropped,
+		Failed:     b.failed,
+		Bytes:      b.bytes,
+		Latency:    latencies(b.latencies),
+		Statuses:   b.statuses,
+		Errors:     b.errors,
+	}
+
+	if f.rate > 0 {
+		r.Model = "open"
+		r.Rate = f.rate
+	}
+
+	if duration > 0 {
+		r.Throughput = float64(r.Requests) / duration.Seconds()
+	}
+
+	r.Retries = max(r.Attempts-int64(r.Requests), 0)
+
+	return r
+}
+
+// latencies - distribution of the latencies
+func latencies(values []time.Duration) latencyReport {
+	if len(values) == 0 {
+		return latencyReport{}
+	}
+
+	sorted := slices.Sorted(slices.Values(values))
+
+	var total time.Duration
+	for _, value := range sorted {
+		total += value
+	}
+
+	return latencyReport{
+		Min:  milliseconds(sorted[0]),
+		Mean: milliseconds(total / time.Duration(len(sorted))),
+		P50:  milliseconds(percentile(sorted, 50)),
+		P90:  milliseconds(percentile(sorted, 90)),
+		P95:  milliseconds(percentile(sorted, 95)),
+		P99:  milliseconds(percentile(sorted, 99)),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile - nearest rank percentile of sorted values
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// print - human readable report
+func (r *benchReport) print(w io.Writer) {
+	model := fmt.Sprintf("closed loop, %d workers", r.Workers)
+	if r.Model == "open" {
+		model = fmt.Sprintf("open loop, %g/s, at most %d in flight", r.Rate, r.Workers)
+	}
+
+	_, _ = fmt.Fprintf(w, "Model      %s\n", model)
+	_, _ = fmt.Fprintf(w, "Requests   %d in %s (%.2f/s), %d failed, %d dropped\n",
+		r.Requests, r.Duration.Round(time.Millisecond), r.Throughput, r.Failed, r.Dropped)
+	_, _ = fmt.Fprintf(w, "Attempts   %d (%d retries)\n", r.Attempts, r.Retries)
+	_, _ = fmt.Fprintf(w, "Received   %d bytes\n", r.Bytes)
+	_, _ = fmt.Fprintf(w, "Latency    min %s, mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+		phase(r.Latency.Min), phase(r.Latency.Mean), phase(r.Latency.P50), phase(r.Latency.P90),
+		phase(r.Latency.P95), phase(r.Latency.P99), phase(r.Latency.Max))
+
+	statuses := make([]string, 0, len(r.Statuses))
+	for _, status := range slices.Sorted(maps.Keys(r.Statuses)) {
+		statuses = append(statuses, fmt.Sprintf("%d: %d", status, r.Statuses[status]))
+	}
+	if len(statuses) == 0 {
+		statuses = append(statuses, "-")
+	}
+	_, _ = fmt.Fprintf(w, "Statuses   %s\n", strings.Join(statuses, ", "))
+
+	for _, message := range slices.Sorted(maps.Keys(r.Errors)) {
+		_, _ = fmt.Fprintf(w, "Error      %d x %s\n", r.Errors[message], message)
+	}
+}
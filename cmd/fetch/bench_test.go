@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestBenchCommand(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("closed loop should send the requested number of requests", func(t *testing.T) {
+			var count atomic.Int32
+			var bodies atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count.Add(1)
+				if body, _ := io.ReadAll(r.Body); string(body) == "payload" && r.Method == http.MethodPost {
+					bodies.Add(1)
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			t.Cleanup(srv.Close)
+
+			code, stdout, _ := runCLI(t, "", "bench", "-n", "20", "-c", "4", "-d", "payload", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, int32(20), count.Load())
+			odize.AssertEqual(t, int32(20), bodies.Load())
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "Model      closed loop, 4 workers\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Requests   20 in "))
+			odize.AssertTrue(t, strings.Contains(stdout, "Attempts   20 (0 retries)\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Received   40 bytes\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Statuses   200: 20\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Latency    min "))
+		}).
+		Test("open loop should start requests at the rate for the duration", func(t *testing.T) {
+			srv, count := statusServer(t, http.StatusOK)
+
+			code, stdout, _ := runCLI(t, "", "bench", "-rate", "100", "-duration", "200ms", "-json", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+
+			var report benchReport
+			odize.AssertNoError(t, json.Unmarshal([]byte(stdout), &report))
+			odize.AssertEqual(t, "open", report.Model)
+			odize.AssertEqual(t, float64(100), report.Rate)
+			odize.AssertEqual(t, int(count.Load()), report.Requests)
+			odize.AssertTrue(t, report.Requests >= 10 && report.Requests <= 21)
+			odize.AssertEqual(t, report.Requests, report.Statuses[http.StatusOK])
+		}).
+		Test("open loop should drop requests over the maximum in flight", func(t *testing.T) {
+			var count atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+				count.Add(1)
+				time.Sleep(100 * time.Millisecond)
+			}))
+			t.Cleanup(srv.Close)
+
+			code, stdout, _ := runCLI(t, "", "bench", "-rate", "200", "-c", "1", "-n", "5", "-json", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+
+			var report benchReport
+			odize.AssertNoError(t, json.Unmarshal([]byte(stdout), &report))
+			odize.AssertEqual(t, 5, report.Requests)
+			odize.AssertTrue(t, report.Dropped > 0)
+			odize.AssertEqual(t, int32(5), count.Load())
+			// every tick either starts or drops a request, dropped ticks do not use up the limit
+			ticks := int(report.DurationMS/1000*200) + 1
+			odize.AssertTrue(t, report.Requests+report.Dropped <= ticks)
+		}).
+		Test("should count retries and fail on error responses", func(t *testing.T) {
+			srv, count := statusServer(t, http.StatusServiceUnavailable, http.StatusOK, http.StatusInternalServerError)
+
+			code, stdout, _ := runCLI(t, "", "bench", "-n", "2", "-c", "1", "-retry", "1ms,1ms", srv.URL)
+			odize.AssertEqual(t, exitError, code)
+			odize.AssertEqual(t, int32(4), count.Load())
+			odize.AssertTrue(t, strings.Contains(stdout, "Requests   2 in "))
+			odize.AssertTrue(t, strings.Contains(stdout, ", 1 failed, 0 dropped\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Attempts   4 (2 retries)\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "Statuses   200: 1, 500: 1\n"))
+		}).
+		Test("should report transport errors", func(t *testing.T) {
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+
+			code, stdout, _ := runCLI(t, "", "bench", "-n", "3", "-c", "1", closed.URL)
+			odize.AssertEqual(t, exitError, code)
+			odize.AssertTrue(t, strings.Contains(stdout, "Error      3 x "))
+			odize.AssertTrue(t, strings.Contains(stdout, "connection refused"))
+		}).
+		Test("invalid arguments should exit with the usage code", func(t *testing.T) {
+			code, _, stderr := runCLI(t, "", "bench")
+			odize.AssertEqual(t, exitUsage, code)
+			odize.AssertTrue(t, strings.Contains(stderr, "Usage: fetch bench [flags] URL"))
+
+			code, _, _ = runCLI(t, "", "bench", "-c", "0", "http://localhost")
+			odize.AssertEqual(t, exitUsage, code)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestPercentile(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should use the nearest rank", func(t *testing.T) {
+			var values []time.Duration
+			for i := 100; i >= 1; i-- {
+				values = append(values, time.Duration(i)*time.Millisecond)
+			}
+
+			report := latencies(values)
+			odize.AssertEqual(t, latencyReport{Min: 1, Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, report)
+			odize.AssertEqual(t, latencyReport{}, latencies(nil))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
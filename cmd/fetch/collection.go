@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// errUndefinedVariable - a template references a variable that is not defined
+	errUndefinedVariable = errors.New("undefined variable")
+	// errUnknownEnvironment - the environment profile is not defined in the collection
+	errUnknownEnvironment = errors.New("unknown environment")
+	// errMissingPath - the JSON path does not exist in the response body
+	errMissingPath = errors.New("path not found")
+)
+
+// collection - requests run in order by fetch run, stored as YAML (.yaml / .yml) or JSON
+type collection struct {
+	Name string `json:"name" yaml:"name"`
+	// Variables available to every request
+	Variables map[string]string `json:"variables" yaml:"variables"`
+	// Environment profiles overriding the variables, selected with -env
+	Environments map[string]map[string]string `json:"environments" yaml:"environments"`
+	Requests     []collectionRequest          `json:"requests" yaml:"requests"`
+}
+
+// collectionRequest - a request and its expectations. Strings may reference variables with {{name}}
+type collectionRequest struct {
+	Name    string            `json:"name" yaml:"name"`
+	Method  string            `json:"method" yaml:"method"`
+	URL     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+	// Raw request body
+	Body string `json:"body" yaml:"body"`
+	// Request body encoded as JSON, takes precedence over Body
+	JSON any `json:"json" yaml:"json"`
+	// Variables captured from the JSON response body by dotted path, e.g. id or items.0.id
+	Capture map[string]string `json:"capture" yaml:"capture"`
+	Expect  expectation       `json:"expect" yaml:"expect"`
+}
+
+// expectation - assertions on the response, a request without expectations passes on any 2xx / 3xx response
+type expectation struct {
+	Status       int               `json:"status" yaml:"status"`
+	Headers      map[string]string `json:"headers" yaml:"headers"`
+	BodyContains string            `json:"body_contains" yaml:"body_contains"`
+	// Expected values by dotted path in the JSON response body
+	JSON map[string]any `json:"json" yaml:"json"`
+}
+
+// loadCollection - read a YAML (.yaml / .yml) or JSON collection
+func loadCollection(path string) (*collection, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var c collection
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &c)
+	default:
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode collection %s: %w", path, err)
+	}
+
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return &c, nil
+}
+
+// variables - collection variables overridden by the environment profile and then the overrides
+func (c *collection) variables(environment string, overrides map[string]string) (map[string]string, error) {
+	variables := map[string]string{}
+	for name, value := range c.Variables {
+		variables[name] = value
+	}
+
+	if environment != "" {
+		profile, ok := c.Environments[environment]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errUnknownEnvironment, environment)
+		}
+		for name, value := range profile {
+			variables[name] = value
+		}
+	}
+
+	for name, value := range overrides {
+		variables[name] = value
+	}
+
+	return variables, nil
+}
+
+var templateVariable = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// expand - replace {{name}} with the variable value
+func expand(template string, variables map[string]string) (string, error) {
+	var missing []string
+	expanded := templateVariable.ReplaceAllStringFunc(template, func(match string) string {
+		name := templateVariable.FindStringSubmatch(match)[1]
+		value, ok := variables[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", errUndefinedVariable, strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// expandAll - expand the strings of a decoded JSON / YAML value
+func expandAll(value any, variables map[string]string) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expand(v, variables)
+	case []any:
+		expanded := make([]any, 0, len(v))
+		for _, item := range v {
+			item, err := expandAll(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, item)
+		}
+		return expanded, nil
+	case map[string]any:
+		expanded := make(map[string]any, len(v))
+		for key, item := range v {
+			item, err := expandAll(item, variables)
+			if err != nil {
+				return nil, err
+			}
+			expanded[key] = item
+		}
+		return expanded, nil
+	default:
+		return value, nil
+	}
+}
+
+// lookup - value at the dotted path, array elements are selected by index
+func lookup(value any, path string) (any, error) {
+	if path == "" {
+		return value, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			item, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", errMissingPath, path)
+			}
+			value = item
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, fmt.Errorf("%w: %s", errMissingPath, path)
+			}
+			value = v[index]
+		default:
+			return nil, fmt.Errorf("%w: %s", errMissingPath, path)
+		}
+	}
+
+	return value, nil
+}
+
+// captured - the value as a variable, strings are used as is and other values as JSON
+func captured(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
+
+// check - failed assertions of the expectation
+func (e expectation) check(resp *http.Response, body []byte, document any, decodeErr error, variables map[string]string) []string {
+	var failures []string
+
+	switch {
+	case e.Status != 0 && resp.StatusCode != e.Status:
+		failures = append(failures, fmt.Sprintf("status: expected %d, received %d", e.Status, resp.StatusCode))
+	case e.Status == 0 && resp.StatusCode > 399:
+		failures = append(failures, fmt.Sprintf("status: expected success, received %d", resp.StatusCode))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(e.Headers)) {
+		expected, err := expand(e.Headers[name], variables)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("header %s: %s", name, err))
+			continue
+		}
+		if actual := resp.Header.Get(name); actual != expected {
+			failures = append(failures, fmt.Sprintf("header %s: expected %q, received %q", name, expected, actual))
+		}
+	}
+
+	if e.BodyContains != "" {
+		expected, err := expand(e.BodyContains, variables)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("body: %s", err))
+		case !strings.Contains(string(body), expected):
+			failures = append(failures, fmt.Sprintf("body: expected to contain %q", expected))
+		}
+	}
+
+	if len(e.JSON) > 0 && decodeErr != nil {
+		return append(failures, fmt.Sprintf("json: %s", decodeErr))
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(e.JSON)) {
+		failures = append(failures, checkJSON(document, path, e.JSON[path], variables)...)
+	}
+
+	return failures
+}
+
+// checkJSON - compare the value at the path with the expected value after expanding its variables
+func checkJSON(document any, path string, expected any, variables map[string]string) []string {
+	actual, err := lookup(document, path)
+	if err != nil {
+		return []string{fmt.Sprintf("json %s: %s", path, err)}
+	}
+
+	expected, err = expandAll(expected, variables)
+	if err != nil {
+		return []string{fmt.Sprintf("json %s: %s", path, err)}
+	}
+
+	// normalise YAML values, e.g. ints, to their JSON representation
+	normalised, err := normaliseJSON(expected)
+	if err != nil {
+		return []string{fmt.Sprintf("json %s: %s", path, err)}
+	}
+
+	if !reflect.DeepEqual(actual, normalised) {
+		return []string{fmt.Sprintf("json %s: expected %s, received %s", path, captured(normalised), captured(actual))}
+	}
+
+	return nil
+}
+
+func normaliseJSON(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalised any
+	err = json.Unmarshal(data, &normalised)
+	return normalised, err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestCollection(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should load YAML and name the collection after the file", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users.yaml")
+			odize.AssertNoError(t, os.WriteFile(path, []byte("requests:\n  - url: http://localhost/users\n    expect:\n      status: 200\n"), 0o600))
+
+			c, err := loadCollection(path)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "users", c.Name)
+			odize.AssertEqual(t, 1, len(c.Requests))
+			odize.AssertEqual(t, 200, c.Requests[0].Expect.Status)
+		}).
+		Test("variables should be overridden by the environment and then the overrides", func(t *testing.T) {
+			c := &collection{
+				Variables:    map[string]string{"host": "localhost", "user": "alice", "token": "secret"},
+				Environments: map[string]map[string]string{"prod": {"host": "example.com", "user": "bob"}},
+			}
+
+			variables, err := c.variables("prod", map[string]string{"user": "carol"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, map[string]string{"host": "example.com", "user": "carol", "token": "secret"}, variables)
+
+			_, err = c.variables("staging", nil)
+			odize.AssertTrue(t, errors.Is(err, errUnknownEnvironment))
+		}).
+		Test("expand should replace variables and report undefined ones", func(t *testing.T) {
+			expanded, err := expand("{{host}}/users/{{ id }}", map[string]string{"host": "http://localhost", "id": "7"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "http://localhost/users/7", expanded)
+
+			_, err = expand("{{host}}/users/{{id}}", map[string]string{})
+			odize.AssertTrue(t, errors.Is(err, errUndefinedVariable))
+			odize.AssertEqual(t, "undefined variable: host, id", err.Error())
+		}).
+		Test("lookup should resolve keys and array indexes", func(t *testing.T) {
+			document := map[string]any{"items": []any{map[string]any{"id": float64(3)}}}
+
+			value, err := lookup(document, "items.0.id")
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "3", captured(value))
+
+			_, err = lookup(document, "items.1.id")
+			odize.AssertTrue(t, errors.Is(err, errMissingPath))
+		}).
+		Test("check should compare YAML values with the JSON body", func(t *testing.T) {
+			e := expectation{
+				Status:       http.StatusOK,
+				BodyContains: "{{name}}",
+				JSON:         map[string]any{"id": 3, "name": "{{name}}", "tags": []any{"a"}},
+			}
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+			body := []byte(`{"id":3,"name":"alice","tags":["a"]}`)
+			document := map[string]any{"id": float64(3), "name": "alice", "tags": []any{"a"}}
+
+			odize.AssertEqual(t, 0, len(e.check(resp, body, document, nil, map[string]string{"name": "alice"})))
+
+			failures := e.check(resp, body, document, nil, map[string]string{"name": "bob"})
+			odize.AssertEqual(t, []string{`body: expected to contain "bob"`, `json name: expected bob, received alice`}, failures)
+		}).
+		Test("check should fail error statuses without an expected status", func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}}
+
+			failures := expectation{}.check(resp, nil, nil, nil, nil)
+			odize.AssertEqual(t, []string{"status: expected success, received 404"}, failures)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
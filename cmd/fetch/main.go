@@ -0,0 +1,108 @@
+// Command fetch sends HTTP requests with the fetch client from the command line.
+//
+// Usage:
+//
+//	fetch [flags] URL
+//	fetch <command> [flags] [args]
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+
+	"github.com/code-gorilla-au/fetch"
+)
+
+// Exit codes
+const (
+	exitOK = 0
+	// transport, file or decoding errors
+	exitError = 1
+	// invalid flags or arguments
+	exitUsage = 2
+	// overall or attempt timeout
+	exitTimeout = 3
+	// 4xx response
+	exitClientError = 4
+	// 5xx response
+	exitServerError = 5
+)
+
+// command - a subcommand, returns the exit code
+type command struct {
+	summary string
+	run     func(ctx context.Context, env *environment, args []string) int
+}
+
+// environment - streams used by a command
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// commands - subcommands by name, arguments without a known command are sent as a request
+var commands = map[string]command{
+	"bench": {summary: "load test a URL, see fetch bench -h", run: benchCommand},
+	"run":   {summary: "run the requests of a collection, see fetch run -h", run: runCommand},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, &environment{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+// run - dispatch to the subcommand, or send a request
+func run(ctx context.Context, env *environment, args []string) int {
+	// the client logs every call and retry, commands report attempts themselves
+	log.SetOutput(io.Discard)
+
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd.run(ctx, env, args[1:])
+		}
+
+		if args[0] == "help" {
+			usage(env.stdout)
+			return exitOK
+		}
+	}
+
+	return requestCommand(ctx, env, args)
+}
+
+// usage - print the commands
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage:")
+	_, _ = fmt.Fprintln(w, "  fetch [flags] URL        send a request, see fetch -h")
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		_, _ = fmt.Fprintf(w, "  fetch %-18s %s\n", name, commands[name].summary)
+	}
+	_, _ = fmt.Fprintln(w, "  fetch help               show this help")
+}
+
+// exitCode - exit code for the error of a call
+func exitCode(err error) int {
+	var apiErr *fetch.APIError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, fetch.ErrTimeout), errors.Is(err, fetch.ErrAttemptTimeout), errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500:
+		return exitServerError
+	case errors.As(err, &apiErr):
+		return exitClientError
+	default:
+		return exitError
+	}
+}
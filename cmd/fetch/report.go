@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// summary - outcome of a collection run, written as the JSON report
+type summary struct {
+	Collection  string        `json:"collection"`
+	Environment string        `json:"environment,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Passed      int           `json:"passed"`
+	Failed      int           `json:"failed"`
+	Duration    time.Duration `json:"-"`
+	DurationMS  float64       `json:"duration_ms"`
+	Results     []*result     `json:"results"`
+}
+
+func summarise(c *collection, environment string, results []*result, duration time.Duration) *summary {
+	s := &summary{
+		Collection:  c.Name,
+		Environment: environment,
+		Timestamp:   time.Now().Add(-duration),
+		Duration:    duration,
+		DurationMS:  milliseconds(duration),
+		Results:     results,
+	}
+
+	for _, r := range results {
+		if r.passed() {
+			s.Passed++
+		} else {
+			s.Failed++
+		}
+	}
+
+	return s
+}
+
+// writeReports - write the JUnit and JSON reports when a path is set
+func (s *summary) writeReports(junitPath, jsonPath string) error {
+	var errs []error
+
+	if junitPath != "" {
+		errs = append(errs, writeReport(junitPath, s.junit, xml.Header))
+	}
+
+	if jsonPath != "" {
+		errs = append(errs, writeReport(jsonPath, func() ([]byte, error) {
+			return json.MarshalIndent(s, "", "  ")
+		}, ""))
+	}
+
+	return errors.Join(errs...)
+}
+
+func writeReport(path string, encode func() ([]byte, error), header string) error {
+	data, err := encode()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Clean(path), append([]byte(header), data...), 0o600)
+}
+
+// junitTestSuites - JUnit XML report, one suite per collection
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junit - the summary as JUnit XML
+func (s *summary) junit() ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      s.Collection,
+		Tests:     len(s.Results),
+		Time:      seconds(s.Duration),
+		Timestamp: s.Timestamp.UTC().Format("2006-01-02T15:04:05"),
+	}
+
+	for _, r := range s.Results {
+		testCase := junitTestCase{Name: r.Name, Classname: s.Collection, Time: seconds(r.Duration)}
+		switch {
+		case r.Error != "":
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: r.Error, Text: r.Error}
+		case len(r.Failures) > 0:
+			suite.Failures++
+			testCase.Failure = &junitProblem{Message: r.Failures[0], Text: strings.Join(r.Failures, "\n")}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	return xml.MarshalIndent(junitTestSuites{
+		Name:     s.Collection,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
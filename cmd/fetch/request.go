@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/code-gorilla-au/fetch"
+)
+
+// headerFlags - repeatable "Name: value" flag
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	return fmt.Sprint(map[string]string(h))
+}
+
+func (h headerFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header %q must be formatted as Name: value", value)
+	}
+
+	h[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	return nil
+}
+
+// durationsFlag - comma separated durations, e.g. 1s,3s,5s
+type durationsFlag []time.Duration
+
+func (d *durationsFlag) String() string {
+	values := make([]string, 0, len(*d))
+	for _, duration := range *d {
+		values = append(values, duration.String())
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (d *durationsFlag) Set(value string) error {
+	*d = nil
+	for _, field := range strings.Split(value, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return err
+		}
+		*d = append(*d, duration)
+	}
+
+	return nil
+}
+
+// clientFlags - client configuration shared by the commands
+type clientFlags struct {
+	headers        headerFlags
+	retry          durationsFlag
+	timeout        time.Duration
+	attemptTimeout time.Duration
+}
+
+// register - add the client flags to the flag set
+func (c *clientFlags) register(flags *flag.FlagSet) {
+	c.headers = headerFlags{}
+	flags.Var(c.headers, "H", "request header `Name: value`, repeatable")
+	flags.Var(&c.retry, "retry", "retry backoff `durations`, one attempt per duration, e.g. 1s,3s,5s. Default is a single attempt")
+	flags.DurationVar(&c.timeout, "timeout", 0, "maximum duration of the whole call, including retries")
+	flags.DurationVar(&c.attemptTimeout, "attempt-timeout", 0, "maximum duration of a single attempt")
+}
+
+// options - client options for the flags
+func (c *clientFlags) options() []fetch.FnOpts {
+	opts := []fetch.FnOpts{
+		fetch.WithTimeout(c.timeout),
+		fetch.WithAttemptTimeout(c.attemptTimeout),
+	}
+
+	if len(c.retry) > 0 {
+		retry := []time.Duration(slices.Clone(c.retry))
+		opts = append(opts, fetch.WithRetryStrategy(&retry))
+	}
+
+	return opts
+}
+
+// requestFlags - flags of the request command
+type requestFlags struct {
+	clientFlags
+	method  string
+	data    string
+	include bool
+	pretty  bool
+	verbose bool
+	output  string
+}
+
+// requestCommand - send a single request and print the response
+func requestCommand(ctx context.Context, env *environment, args []string) int {
+	var f requestFlags
+	flags := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(env.stderr, "Usage: fetch [flags] URL")
+		flags.PrintDefaults()
+		_, _ = fmt.Fprintln(env.stderr, "\nExit codes: 0 success, 1 error, 2 usage, 3 timeout, 4 4xx response, 5 5xx response")
+	}
+
+	f.register(flags)
+	flags.StringVar(&f.method, "X", "", "request `method`, default is GET, or POST with -d")
+	flags.StringVar(&f.data, "d", "", "request body, @path reads a file and @- reads stdin")
+	flags.BoolVar(&f.include, "i", false, "print the status line and response headers")
+	flags.BoolVar(&f.pretty, "pretty", false, "pretty print JSON response bodies")
+	flags.BoolVar(&f.verbose, "v", false, "print the request, each attempt with its timings, and the response headers to stderr")
+	flags.StringVar(&f.output, "o", "", "write the response body to the `file`")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	return sendRequest(ctx, env, &f, positional[0])
+}
+
+// sendRequest - send the request described by the flags
+func sendRequest(ctx context.Context, env *environment, f *requestFlags, url string) int {
+	body, closeBody, err := openBody(env.stdin, f.data)
+	if err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitError
+	}
+	defer closeBody()
+
+	method := strings.ToUpper(f.method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	recorder := fetch.NewHARRecorder(0)
+	client := fetch.New(fetch.WithOpts(append(f.options(), fetch.WithHAR(recorder))...))
+
+	start := time.Now()
+	resp, err := client.Do(ctx, &fetch.Request{Method: method, URL: url, Body: body, Headers: f.headers})
+	hasResponse := resp != nil && resp.Body != nil && resp.StatusCode != 0
+
+	if f.verbose {
+		printRequest(env.stderr, recorder)
+	}
+
+	var writeErr error
+	if hasResponse {
+		defer resp.Body.Close()
+		writeErr = writeResponse(env, f, resp)
+	}
+
+	if f.verbose {
+		printAttempts(env.stderr, recorder, time.Since(start))
+	}
+
+	if err != nil && !hasResponse {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitCode(err)
+	}
+
+	if writeErr != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", writeErr)
+		if err == nil {
+			return exitError
+		}
+	}
+
+	return exitCode(err)
+}
+
+// openBody - the request body from the -d flag, nil when empty
+func openBody(stdin io.Reader, data string) (io.Reader, func(), error) {
+	noop := func() {}
+
+	switch {
+	case data == "":
+		return nil, noop, nil
+	case data == "@-":
+		// buffer stdin so the body can be replayed on retry
+		content, err := io.ReadAll(stdin)
+		return bytes.NewReader(content), noop, err
+	case strings.HasPrefix(data, "@"):
+		file, err := os.Open(filepath.Clean(data[1:]))
+		if err != nil {
+			return nil, noop, err
+		}
+		closeFile := func() { _ = file.Close() }
+
+		info, err := file.Stat()
+		if err != nil {
+			closeFile()
+			return nil, noop, err
+		}
+		// a section reader is seeked on retry and not closed by the client, unlike the file
+		return io.NewSectionReader(file, 0, info.Size()), closeFile, nil
+	default:
+		return strings.NewReader(data), noop, nil
+	}
+}
+
+// writeResponse - print the status line and headers when requested, then the body
+func writeResponse(env *environment, f *requestFlags, resp *http.Response) error {
+	if f.verbose {
+		printHeaders(env.stderr, "< ", resp)
+	}
+
+	if f.include {
+		printHeaders(env.stdout, "", resp)
+		_, _ = fmt.Fprintln(env.stdout)
+	}
+
+	out := env.stdout
+	if f.output != "" {
+		file, err := os.Create(filepath.Clean(f.output))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if !f.pretty {
+		_, err := io.Copy(out, resp.Body)
+		return err
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, content, "", "  ") == nil {
+		content = append(pretty.Bytes(), '\n')
+	}
+
+	_, err = out.Write(content)
+	return err
+}
+
+// printHeaders - status line and headers of the response
+func printHeaders(w io.Writer, prefix string, resp *http.Response) {
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", prefix, resp.Proto, resp.Status)
+	for _, name := range slices.Sorted(maps.Keys(resp.Header)) {
+		for _, value := range resp.Header[name] {
+			_, _ = fmt.Fprintf(w, "%s%s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+// printRequest - request line and headers of the first attempt
+func printRequest(w io.Writer, recorder *fetch.HARRecorder) {
+	entries := recorder.HAR().Log.Entries
+	if len(entries) == 0 {
+		return
+	}
+
+	request := entries[0].Request
+	_, _ = fmt.Fprintf(w, "> %s %s\n", request.Method, request.URL)
+	for _, header := range request.Headers {
+		_, _ = fmt.Fprintf(w, "> %s: %s\n", header.Name, header.Value)
+	}
+}
+
+// printAttempts - outcome and timings of each attempt
+func printAttempts(w io.Writer, recorder *fetch.HARRecorder, total time.Duration) {
+	for _, entry := range recorder.HAR().Log.Entries {
+		outcome := entry.Error
+		if outcome == "" {
+			outcome = fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText)
+		}
+
+		timings := entry.Timings
+		_, _ = fmt.Fprintf(w, "* attempt %d: %s in %s (dns %s, connect %s, tls %s, send %s, wait %s, receive %s)\n",
+			entry.Attempt, outcome, phase(entry.Time),
+			phase(timings.DNS), phase(timings.Connect), phase(timings.SSL),
+			phase(timings.Send), phase(timings.Wait), phase(timings.Receive))
+	}
+
+	_, _ = fmt.Fprintf(w, "* total %s\n", total.Round(time.Microsecond))
+}
+
+// phase - HAR milliseconds as a duration, - when the phase does not apply
+func phase(ms float64) string {
+	if ms < 0 {
+		return "-"
+	}
+
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
+
+// parseInterspersed - parse flags placed before and after positional arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
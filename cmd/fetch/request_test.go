@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// runCLI - run the command line with the stdin, returns the exit code, stdout and stderr
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), &environment{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// echoServer - replies with the method, the X-Test header and the body
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		_, _ = w.Write([]byte(r.Header.Get("X-Test") + ":" + string(body)))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// statusServer - replies with the statuses in order, repeating the last
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var count atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := int(count.Add(1))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+		_, _ = w.Write([]byte(`{"attempt":` + strconv.Itoa(n) + `}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &count
+}
+
+func TestRequestCommand(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should send headers and a body with flags after the URL", func(t *testing.T) {
+			srv := echoServer(t)
+
+			code, stdout, _ := runCLI(t, "", srv.URL, "-H", "X-Test: yes", "-d", "payload")
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, "yes:payload", stdout)
+		}).
+		Test("should read the body from stdin and a file", func(t *testing.T) {
+			srv := echoServer(t)
+
+			code, stdout, _ := runCLI(t, "from stdin", "-X", "put", "-d", "@-", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, ":from stdin", stdout)
+
+			path := filepath.Join(t.TempDir(), "body.txt")
+			odize.AssertNoError(t, os.WriteFile(path, []byte("from file"), 0o600))
+
+			code, stdout, _ = runCLI(t, "", "-d", "@"+path, srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, ":from file", stdout)
+		}).
+		Test("file bodies should be sent again on retry", func(t *testing.T) {
+			var bodies []string
+			var count atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if count.Add(1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			path := filepath.Join(t.TempDir(), "body.txt")
+			odize.AssertNoError(t, os.WriteFile(path, []byte("from file"), 0o600))
+
+			code, _, stderr := runCLI(t, "", "-d", "@"+path, "-retry", "1ms,1ms", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, "", stderr)
+			odize.AssertEqual(t, []string{"from file", "from file"}, bodies)
+		}).
+		Test("should print the status line and headers", func(t *testing.T) {
+			srv := echoServer(t)
+
+			code, stdout, _ := runCLI(t, "", "-i", "-X", "DELETE", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "HTTP/1.1 200 OK\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "X-Method: DELETE\n"))
+			odize.AssertTrue(t, strings.HasSuffix(stdout, "\n\n:"))
+		}).
+		Test("should pretty print JSON and write to a file", func(t *testing.T) {
+			srv, _ := statusServer(t, http.StatusOK)
+			path := filepath.Join(t.TempDir(), "out.json")
+
+			code, stdout, _ := runCLI(t, "", "-pretty", "-o", path, srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, "", stdout)
+
+			data, err := os.ReadFile(path)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "{\n  \"attempt\": 1\n}\n", string(data))
+		}).
+		Test("verbose mode should report each attempt", func(t *testing.T) {
+			srv, count := statusServer(t, http.StatusServiceUnavailable, http.StatusOK)
+
+			code, stdout, stderr := runCLI(t, "", "-v", "-retry", "1ms,1ms,1ms", srv.URL)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertEqual(t, int32(2), count.Load())
+			odize.AssertEqual(t, `{"attempt":2}`, stdout)
+			odize.AssertTrue(t, strings.Contains(stderr, "> GET "+srv.URL))
+			odize.AssertTrue(t, strings.Contains(stderr, "* attempt 1: 503 Service Unavailable in "))
+			odize.AssertTrue(t, strings.Contains(stderr, "* attempt 2: 200 OK in "))
+			odize.AssertTrue(t, strings.Contains(stderr, "< HTTP/1.1 200 OK"))
+			odize.AssertTrue(t, strings.Contains(stderr, "* total "))
+		}).
+		Test("API errors should map to exit codes and still print the body", func(t *testing.T) {
+			srv, _ := statusServer(t, http.StatusNotFound)
+			code, stdout, _ := runCLI(t, "", srv.URL)
+			odize.AssertEqual(t, exitClientError, code)
+			odize.AssertEqual(t, `{"attempt":1}`, stdout)
+
+			srv, count := statusServer(t, http.StatusBadGateway)
+			code, _, _ = runCLI(t, "", "-retry", "1ms,1ms", srv.URL)
+			odize.AssertEqual(t, exitServerError, code)
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("timeouts and transport errors should map to exit codes", func(t *testing.T) {
+			slow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			}))
+			t.Cleanup(slow.Close)
+
+			code, _, stderr := runCLI(t, "", "-timeout", "20ms", slow.URL)
+			odize.AssertEqual(t, exitTimeout, code)
+			odize.AssertTrue(t, strings.HasPrefix(stderr, "fetch: "))
+
+			closed := httptest.NewServer(http.NotFoundHandler())
+			closed.Close()
+			code, _, _ = runCLI(t, "", closed.URL)
+			odize.AssertEqual(t, exitError, code)
+		}).
+		Test("invalid arguments should exit with the usage code", func(t *testing.T) {
+			code, _, stderr := runCLI(t, "")
+			odize.AssertEqual(t, exitUsage, code)
+			odize.AssertTrue(t, strings.Contains(stderr, "Usage: fetch [flags] URL"))
+
+			code, _, _ = runCLI(t, "", "-H", "missing-colon", "http://localhost")
+			odize.AssertEqual(t, exitUsage, code)
+
+			code, _, _ = runCLI(t, "", "-retry", "soon", "http://localhost")
+			odize.AssertEqual(t, exitUsage, code)
+
+			code, stdout, _ := runCLI(t, "", "help")
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "Usage:"))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
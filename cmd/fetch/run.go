@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/code-gorilla-au/fetch"
+)
+
+// variableFlags - repeatable name=value flag
+type variableFlags map[string]string
+
+func (v variableFlags) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v variableFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("variable %q must be formatted as name=value", value)
+	}
+
+	v[name] = val
+	return nil
+}
+
+// runFlags - flags of the run command
+type runFlags struct {
+	clientFlags
+	environment string
+	variables   variableFlags
+	junit       string
+	json        string
+	failFast    bool
+}
+
+// result - outcome of a collection request
+type result struct {
+	Name       string        `json:"name"`
+	Method     string        `json:"method"`
+	URL        string        `json:"url"`
+	Status     int           `json:"status,omitempty"`
+	Duration   time.Duration `json:"-"`
+	DurationMS float64       `json:"duration_ms"`
+	// Failed assertions
+	Failures []string `json:"failures,omitempty"`
+	// The request could not be built or sent
+	Error string `json:"error,omitempty"`
+}
+
+func (r *result) passed() bool {
+	return r.Error == "" && len(r.Failures) == 0
+}
+
+// runCommand - run the requests of a collection in order
+func runCommand(ctx context.Context, env *environment, args []string) int {
+	f := runFlags{variables: variableFlags{}}
+	flags := flag.NewFlagSet("fetch run", flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		_, _ = fmt.Fprintln(env.stderr, "Usage: fetch run [flags] COLLECTION")
+		flags.PrintDefaults()
+	}
+
+	f.register(flags)
+	flags.StringVar(&f.environment, "env", "", "environment `profile` of the collection")
+	flags.Var(f.variables, "var", "variable `name=value`, overrides the collection and environment, repeatable")
+	flags.StringVar(&f.junit, "junit", "", "write a JUnit XML report to the `file`")
+	flags.StringVar(&f.json, "json", "", "write a JSON report to the `file`")
+	flags.BoolVar(&f.failFast, "fail-fast", false, "stop at the first failed request")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	c, err := loadCollection(positional[0])
+	if err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitError
+	}
+
+	variables, err := c.variables(f.environment, f.variables)
+	if err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitUsage
+	}
+
+	client := fetch.New(fetch.WithOpts(append(f.options(), fetch.WithHeaders(f.headers))...))
+
+	start := time.Now()
+	var results []*result
+	for i := range c.Requests {
+		r := runRequest(ctx, client, &c.Requests[i], variables)
+		results = append(results, r)
+		printResult(env.stdout, r)
+
+		if ctx.Err() != nil || (f.failFast && !r.passed()) {
+			break
+		}
+	}
+
+	summary := summarise(c, f.environment, results, time.Since(start))
+	_, _ = fmt.Fprintf(env.stdout, "\n%d passed, %d failed in %s\n", summary.Passed, summary.Failed, summary.Duration.Round(time.Millisecond))
+
+	if err = summary.writeReports(f.junit, f.json); err != nil {
+		_, _ = fmt.Fprintln(env.stderr, "fetch:", err)
+		return exitError
+	}
+
+	if summary.Failed > 0 {
+		return exitError
+	}
+
+	return exitOK
+}
+
+// runRequest - send the request, check its expectations and capture variables
+func runRequest(ctx context.Context, client *fetch.Client, request *collectionRequest, variables map[string]string) *result {
+	r := &result{Name: request.Name}
+
+	req, err := buildRequest(request, variables)
+	if req != nil {
+		r.Method, r.URL = req.Method, req.URL
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimSpace(r.Method + " " + r.URL)
+	}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	start := time.Now()
+	resp, err := client.Do(ctx, req)
+	var apiErr *fetch.APIError
+	if err != nil && !errors.As(err, &apiErr) {
+		r.Duration = time.Since(start)
+		r.DurationMS = milliseconds(r.Duration)
+		discardResponse(resp)
+		r.Error = err.Error()
+		return r
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	r.Duration = time.Since(start)
+	r.DurationMS = milliseconds(r.Duration)
+	r.Status = resp.StatusCode
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+
+	var document any
+	decodeErr := json.Unmarshal(body, &document)
+
+	r.Failures = request.Expect.check(resp, body, document, decodeErr, variables)
+
+	for _, name := range slices.Sorted(maps.Keys(request.Capture)) {
+		if decodeErr != nil {
+			r.Failures = append(r.Failures, fmt.Sprintf("capture %s: %s", name, decodeErr))
+			continue
+		}
+
+		value, err := lookup(document, request.Capture[name])
+		if err != nil {
+			r.Failures = append(r.Failures, fmt.Sprintf("capture %s: %s", name, err))
+			continue
+		}
+		variables[name] = captured(value)
+	}
+
+	return r
+}
+
+// buildRequest - expand the variables of the request
+func buildRequest(request *collectionRequest, variables map[string]string) (*fetch.Request, error) {
+	url, err := expand(request.URL, variables)
+	if err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+
+	req := &fetch.Request{Method: strings.ToUpper(request.Method), URL: url, Headers: map[string]string{}}
+
+	for name, value := range request.Headers {
+		if req.Headers[name], err = expand(value, variables); err != nil {
+			return req, fmt.Errorf("header %s: %w", name, err)
+		}
+	}
+
+	var body []byte
+	switch {
+	case request.JSON != nil:
+		value, err := expandAll(request.JSON, variables)
+		if err != nil {
+			return req, fmt.Errorf("json: %w", err)
+		}
+		if body, err = json.Marshal(value); err != nil {
+			return req, fmt.Errorf("json: %w", err)
+		}
+		if _, ok := req.Headers["Content-Type"]; !ok {
+			req.Headers["Content-Type"] = "application/json"
+		}
+	case request.Body != "":
+		expanded, err := expand(request.Body, variables)
+		if err != nil {
+			return req, fmt.Errorf("body: %w", err)
+		}
+		body = []byte(expanded)
+	}
+
+	if body != nil {
+		req.Body = bytes.NewReader(body)
+	}
+
+	if req.Method == "" {
+		req.Method = http.MethodGet
+		if body != nil {
+			req.Method = http.MethodPost
+		}
+	}
+
+	return req, nil
+}
+
+// printResult - one line per request, followed by its failures
+func printResult(w io.Writer, r *result) {
+	switch {
+	case r.Error != "":
+		_, _ = fmt.Fprintf(w, "ERROR %s: %s\n", r.Name, r.Error)
+	case r.passed():
+		_, _ = fmt.Fprintf(w, "PASS  %s (%d, %s)\n", r.Name, r.Status, r.Duration.Round(time.Millisecond))
+	default:
+		_, _ = fmt.Fprintf(w, "FAIL  %s (%d, %s)\n", r.Name, r.Status, r.Duration.Round(time.Millisecond))
+	}
+
+	for _, failure := range r.Failures {
+		_, _ = fmt.Fprintf(w, "      %s\n", failure)
+	}
+}
+
+// discardResponse - close the body of a response that is not used
+func discardResponse(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
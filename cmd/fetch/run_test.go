@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// usersServer - creates a user and returns it by id, requires the bearer token
+func usersServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/users":
+			var user map[string]any
+			if json.NewDecoder(r.Body).Decode(&user) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			user["id"] = "u-42"
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(user)
+		case r.Method == http.MethodGet && r.URL.Path == "/users/u-42":
+			_, _ = io.WriteString(w, `{"id":"u-42","name":"alice","roles":["admin"]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+const usersCollection = `name: users
+variables:
+  host: http://localhost:1
+environments:
+  local:
+    token: secret
+requests:
+  - name: create user
+    url: "{{host}}/users"
+    headers:
+      Authorization: Bearer {{token}}
+    json:
+      name: alice
+    capture:
+      user_id: id
+    expect:
+      status: 201
+      headers:
+        Content-Type: application/json
+  - name: get user
+    url: "{{host}}/users/{{user_id}}"
+    headers:
+      Authorization: Bearer {{token}}
+    expect:
+      json:
+        name: alice
+        roles.0: "{{role}}"
+`
+
+func writeCollection(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	odize.AssertNoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestRunCommand(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should chain captured variables with the environment and overrides", func(t *testing.T) {
+			srv := usersServer(t)
+			path := writeCollection(t, "users.yaml", usersCollection)
+
+			code, stdout, stderr := runCLI(t, "", "run", path, "-env", "local", "-var", "host="+srv.URL, "-var", "role=admin")
+			odize.AssertEqual(t, "", stderr)
+			odize.AssertEqual(t, exitOK, code)
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "PASS  create user (201, "))
+			odize.AssertTrue(t, strings.Contains(stdout, "\nPASS  get user (200, "))
+			odize.AssertTrue(t, strings.Contains(stdout, "\n2 passed, 0 failed in "))
+		}).
+		Test("failed assertions should exit with an error and be reported", func(t *testing.T) {
+			srv := usersServer(t)
+			path := writeCollection(t, "users.yaml", usersCollection)
+			dir := t.TempDir()
+			junitPath := filepath.Join(dir, "junit.xml")
+			jsonPath := filepath.Join(dir, "report.json")
+
+			code, stdout, _ := runCLI(t, "", "run", "-env", "local", "-var", "host="+srv.URL, "-var", "role=guest",
+				"-junit", junitPath, "-json", jsonPath, path)
+			odize.AssertEqual(t, exitError, code)
+			odize.AssertTrue(t, strings.Contains(stdout, "FAIL  get user (200, "))
+			odize.AssertTrue(t, strings.Contains(stdout, "      json roles.0: expected guest, received admin\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "\n1 passed, 1 failed in "))
+
+			data, err := os.ReadFile(junitPath)
+			odize.AssertNoError(t, err)
+			var suites junitTestSuites
+			odize.AssertNoError(t, xml.Unmarshal(data, &suites))
+			odize.AssertEqual(t, 2, suites.Tests)
+			odize.AssertEqual(t, 1, suites.Failures)
+			odize.AssertEqual(t, "users", suites.Suites[0].Name)
+			odize.AssertNil(t, suites.Suites[0].Cases[0].Failure)
+			odize.AssertEqual(t, "json roles.0: expected guest, received admin", suites.Suites[0].Cases[1].Failure.Message)
+
+			data, err = os.ReadFile(jsonPath)
+			odize.AssertNoError(t, err)
+			var report summary
+			odize.AssertNoError(t, json.Unmarshal(data, &report))
+			odize.AssertEqual(t, "local", report.Environment)
+			odize.AssertEqual(t, 1, report.Passed)
+			odize.AssertEqual(t, 1, report.Failed)
+			odize.AssertEqual(t, srv.URL+"/users/u-42", report.Results[1].URL)
+			odize.AssertEqual(t, http.StatusOK, report.Results[1].Status)
+		}).
+		Test("fail fast should stop at the first failure", func(t *testing.T) {
+			srv := usersServer(t)
+			path := writeCollection(t, "users.yaml", usersCollection)
+
+			code, stdout, _ := runCLI(t, "", "run", "-fail-fast", "-var", "host="+srv.URL, "-var", "token=wrong", path)
+			odize.AssertEqual(t, exitError, code)
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "FAIL  create user (401, "))
+			odize.AssertTrue(t, strings.Contains(stdout, "      status: expected 201, received 401\n"))
+			odize.AssertTrue(t, strings.Contains(stdout, "      capture user_id: "))
+			odize.AssertFalse(t, strings.Contains(stdout, "get user"))
+		}).
+		Test("undefined variables should error the request", func(t *testing.T) {
+			path := writeCollection(t, "users.json", `{"requests":[{"name":"missing","url":"{{host}}/users"}]}`)
+
+			code, stdout, _ := runCLI(t, "", "run", path)
+			odize.AssertEqual(t, exitError, code)
+			odize.AssertTrue(t, strings.HasPrefix(stdout, "ERROR missing: url: undefined variable: host\n"))
+		}).
+		Test("invalid arguments should exit with the usage code", func(t *testing.T) {
+			code, _, stderr := runCLI(t, "", "run")
+			odize.AssertEqual(t, exitUsage, code)
+			odize.AssertTrue(t, strings.Contains(stderr, "Usage: fetch run [flags] COLLECTION"))
+
+			path := writeCollection(t, "users.yaml", usersCollection)
+			code, _, stderr = runCLI(t, "", "run", "-env", "staging", path)
+			odize.AssertEqual(t, exitUsage, code)
+			odize.AssertEqual(t, "fetch: unknown environment: staging\n", stderr)
+
+			code, _, _ = runCLI(t, "", "run", "-var", "novalue", path)
+			odize.AssertEqual(t, exitUsage, code)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,161 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Coalescer - shares one in flight GET / HEAD request between concurrent identical requests.
+// Requests are identical when the method, URL and selected headers match.
+type Coalescer struct {
+	// Headers included in the key, e.g. Authorization or Accept
+	Headers []string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight - a shared in flight request
+type flight struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+	resp    *http.Response
+	body    []byte
+	err     error
+}
+
+// NewCoalescer - returns a coalescer keyed by method, URL and the provided headers
+func NewCoalescer(headers ...string) *Coalescer {
+	return &Coalescer{
+		Headers: headers,
+		flights: map[string]*flight{},
+	}
+}
+
+// WithRequestCoalescing - concurrent identical GET / HEAD requests share one in flight request.
+// Requests are identical when the method, URL and provided headers match.
+// Each caller receives its own copy of the body. The shared request is only cancelled once every caller has gone.
+func WithRequestCoalescing(headers ...string) FnOpts {
+	return func(o *Options) error {
+		o.Coalescer = NewCoalescer(headers...)
+		return nil
+	}
+}
+
+// doCoalesced - join or start the shared request for r
+func (a *Client) doCoalesced(ctx context.Context, r *Request, fn func(ctx context.Context, r *Request) (*http.Response, error)) (*http.Response, error) {
+	if r.Body != nil || r.GetBody != nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return fn(ctx, r)
+	}
+
+	c := a.Coalescer
+	key := c.key(r, a.DefaultHeaders)
+
+	c.mu.Lock()
+	if c.flights == nil {
+		c.flights = map[string]*flight{}
+	}
+	f, ok := c.flights[key]
+	if !ok {
+		f = c.start(ctx, key, r, fn)
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.response(), f.err
+	case <-ctx.Done():
+		c.leave(key, f)
+		return nil, context.Cause(ctx)
+	}
+}
+
+// start - send the shared request, detached from the caller's cancellation. Callers must hold the lock
+func (c *Coalescer) start(ctx context.Context, key string, r *Request, fn func(ctx context.Context, r *Request) (*http.Response, error)) *flight {
+	sharedCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &flight{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	c.flights[key] = f
+
+	go func() {
+		defer cancel()
+
+		resp, err := fn(sharedCtx, r)
+		if resp != nil && resp.Body != nil {
+			body, readErr := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err == nil {
+				err = readErr
+			}
+			f.body = body
+		}
+		f.resp = resp
+		f.err = err
+
+		c.mu.Lock()
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mu.Unlock()
+
+		close(f.done)
+	}()
+
+	return f
+}
+
+// leave - a caller stopped waiting, the shared request is cancelled when no callers remain
+func (c *Coalescer) leave(key string, f *flight) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+	f.cancel()
+}
+
+// key - method, URL and the selected header values
+func (c *Coalescer) key(r *Request, defaults map[string]string) string {
+	header := requestHeader(r, defaults)
+
+	names := make([]string, 0, len(c.Headers))
+	for _, name := range c.Headers {
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	builder.WriteString(r.Method + " " + r.URL)
+	for _, name := range names {
+		builder.WriteString("\n" + name + ": " + strings.Join(header.Values(name), ","))
+	}
+
+	return builder.String()
+}
+
+// response - an independent copy of the shared response
+func (f *flight) response() *http.Response {
+	if f.resp == nil {
+		return nil
+	}
+
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+	return &resp
+}
@@ -0,0 +1,184 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// waitForWaiters - block until n callers share the in flight request for key
+func waitForWaiters(t *testing.T, c *Coalescer, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		waiters := 0
+		for _, f := range c.flights {
+			waiters += f.waiters
+		}
+		c.mu.Unlock()
+
+		if waiters == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d waiters", n)
+}
+
+func TestClient_request_coalescing(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithRequestCoalescing("Authorization")))
+	})
+
+	err := group.
+		Test("concurrent identical requests should share one request", func(t *testing.T) {
+			release := make(chan struct{})
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				<-release
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			const callers = 10
+			bodies := make([]string, callers)
+			var wg sync.WaitGroup
+			for i := range callers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, err := client.Get(srv.URL, nil)
+					odize.AssertNoError(t, err)
+					bodies[i] = readBody(t, resp)
+				}()
+			}
+
+			waitForWaiters(t, client.Coalescer, callers)
+			close(release)
+			wg.Wait()
+
+			odize.AssertEqual(t, int32(1), count.Load())
+			for _, body := range bodies {
+				odize.AssertEqual(t, "hello", body)
+			}
+		}).
+		Test("different selected headers should not share a request", func(t *testing.T) {
+			release := make(chan struct{})
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				<-release
+				_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+			})
+
+			bodies := make([]string, 2)
+			var wg sync.WaitGroup
+			for i, token := range []string{"a", "b"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, err := client.Get(srv.URL, map[string]string{"Authorization": token})
+					odize.AssertNoError(t, err)
+					bodies[i] = readBody(t, resp)
+				}()
+			}
+
+			waitForWaiters(t, client.Coalescer, 2)
+			close(release)
+			wg.Wait()
+
+			odize.AssertEqual(t, int32(2), count.Load())
+			odize.AssertEqual(t, []string{"a", "b"}, bodies)
+		}).
+		Test("cancelled waiter should not cancel the shared request", func(t *testing.T) {
+			release := make(chan struct{})
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				<-release
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelled := make(chan error, 1)
+			go func() {
+				_, err := client.GetCtx(ctx, srv.URL, nil)
+				cancelled <- err
+			}()
+
+			var body string
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				body = readBody(t, resp)
+			}()
+
+			waitForWaiters(t, client.Coalescer, 2)
+			cancel()
+			odize.AssertTrue(t, errors.Is(<-cancelled, context.Canceled))
+
+			close(release)
+			<-done
+			odize.AssertEqual(t, "hello", body)
+		}).
+		Test("shared request should be cancelled once every waiter has gone", func(t *testing.T) {
+			serverCancelled := make(chan struct{})
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, r *http.Request, _ int32) {
+				<-r.Context().Done()
+				close(serverCancelled)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			for range 2 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.GetCtx(ctx, srv.URL, nil)
+					odize.AssertTrue(t, errors.Is(err, context.Canceled))
+				}()
+			}
+
+			waitForWaiters(t, client.Coalescer, 2)
+			cancel()
+			wg.Wait()
+
+			select {
+			case <-serverCancelled:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the shared request to be cancelled")
+			}
+		}).
+		Test("requests with a body should not be coalesced", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte("created"))
+			})
+
+			resp, err := client.Post(srv.URL, nil, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "created", readBody(t, resp))
+			odize.AssertEqual(t, int32(1), count.Load())
+			odize.AssertEqual(t, 0, len(client.Coalescer.flights))
+		}).
+		Test("api errors should be shared with every waiter", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("missing"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, http.StatusNotFound, apiErr.StatusCode)
+			odize.AssertEqual(t, "missing", readBody(t, resp))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,123 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionGzip - gzip request body compression
+	CompressionGzip = "gzip"
+	// CompressionZstd - zstd request body compression
+	CompressionZstd = "zstd"
+)
+
+// ErrUnsupportedCompression - the request body compression encoding is not supported
+var ErrUnsupportedCompression = errors.New("unsupported request compression")
+
+// RequestCompression - compress request bodies before sending
+type RequestCompression struct {
+	// Content coding, CompressionGzip or CompressionZstd
+	Encoding string
+	// Bodies smaller than MinSize bytes are sent uncompressed
+	MinSize int64
+}
+
+// WithRequestCompression - compress request bodies of at least minSize bytes with encoding (CompressionGzip or CompressionZstd) and set Content-Encoding.
+// The body is compressed once and reused across retries. If the server replies 415 Unsupported Media Type the request is sent again uncompressed,
+// within the same overall timeout. Requests that already set Content-Encoding, and bodies streamed from Request.GetBody such as multipart forms, are sent as is.
+func WithRequestCompression(encoding string, minSize int64) FnOpts {
+	return func(o *Options) error {
+		if encoding != CompressionGzip && encoding != CompressionZstd {
+			return fmt.Errorf("%w: %q", ErrUnsupportedCompression, encoding)
+		}
+		o.RequestCompression = &RequestCompression{Encoding: encoding, MinSize: minSize}
+		return nil
+	}
+}
+
+// sendCompressed - send the request with a compressed body, falling back to the uncompressed body on 415.
+// Both sends share the overall timeout
+func (a *Client) sendCompressed(ctx context.Context, r *Request) (*http.Response, error) {
+	if r.GetBody != nil || requestHeader(r, a.DefaultHeaders).Get("Content-Encoding") != "" {
+		return a.transmit(ctx, r)
+	}
+
+	if length, ok := bodyLength(r.Body); ok && length < a.RequestCompression.MinSize {
+		return a.transmit(ctx, r)
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &http.Response{}, err
+	}
+
+	plain := *r
+	plain.Body = bytes.NewReader(data)
+	if int64(len(data)) < a.RequestCompression.MinSize {
+		return a.transmit(ctx, &plain)
+	}
+
+	compressed, err := a.RequestCompression.compress(data)
+	if err != nil {
+		return &http.Response{}, err
+	}
+
+	encoded := *r
+	encoded.Body = bytes.NewReader(compressed)
+	encoded.Headers = mergeHeaders(r.Headers, map[string]string{"Content-Encoding": a.RequestCompression.Encoding})
+
+	ctx, cancel := a.withTimeout(ctx, r)
+	resp, err := a.exchange(ctx, &encoded)
+	if resp != nil && resp.StatusCode == http.StatusUnsupportedMediaType {
+		discard(resp)
+		resp, err = a.exchange(ctx, &plain)
+	}
+
+	return releaseOnClose(resp, cancel), err
+}
+
+// bodyLength - length of a body held in memory, such as *bytes.Reader, *strings.Reader or *bytes.Buffer
+func bodyLength(body io.Reader) (int64, bool) {
+	if sized, ok := body.(interface{ Len() int }); ok {
+		return int64(sized.Len()), true
+	}
+
+	return 0, false
+}
+
+// compress - compress the body with the configured encoding
+func (c *RequestCompression) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch c.Encoding {
+	case CompressionGzip:
+		writer = gzip.NewWriter(&buf)
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(&buf, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		writer = encoder
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCompression, c.Encoding)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		_ = writer.Close()
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
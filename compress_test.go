@@ -0,0 +1,196 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decodeRequestBody - read the request body, decoding it according to Content-Encoding
+func decodeRequestBody(t *testing.T, r *http.Request) string {
+	t.Helper()
+
+	var reader io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(r.Body)
+		odize.AssertNoError(t, err)
+		reader = gz
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r.Body)
+		odize.AssertNoError(t, err)
+		defer zr.Close()
+		reader = zr
+	}
+
+	data, err := io.ReadAll(reader)
+	odize.AssertNoError(t, err)
+	return string(data)
+}
+
+func TestClient_request_compression(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	payload := strings.Repeat(`{"id":1,"name":"batch"}`, 100)
+
+	err := group.
+		Test("body over the threshold should be gzip compressed", func(t *testing.T) {
+			var encoding, received string
+			var length int64
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				encoding = r.Header.Get("Content-Encoding")
+				length = r.ContentLength
+				received = decodeRequestBody(t, r)
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionGzip, 1024)))
+			resp, err := client.Post(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, CompressionGzip, encoding)
+			odize.AssertEqual(t, payload, received)
+			odize.AssertTrue(t, length > 0 && length < int64(len(payload)))
+		}).
+		Test("body over the threshold should be zstd compressed", func(t *testing.T) {
+			var encoding, received string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				encoding = r.Header.Get("Content-Encoding")
+				received = decodeRequestBody(t, r)
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionZstd, 0)))
+			resp, err := client.Put(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, CompressionZstd, encoding)
+			odize.AssertEqual(t, payload, received)
+		}).
+		Test("body under the threshold should be sent uncompressed", func(t *testing.T) {
+			var encoding, received string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				encoding = r.Header.Get("Content-Encoding")
+				received = decodeRequestBody(t, r)
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionGzip, int64(len(payload)+1))))
+			resp, err := client.Post(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, "", encoding)
+			odize.AssertEqual(t, payload, received)
+		}).
+		Test("415 should fall back to the uncompressed body", func(t *testing.T) {
+			var encodings []string
+			var received string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				encodings = append(encodings, r.Header.Get("Content-Encoding"))
+				if r.Header.Get("Content-Encoding") != "" {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				received = decodeRequestBody(t, r)
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionGzip, 0)))
+			resp, err := client.Post(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, []string{CompressionGzip, ""}, encodings)
+			odize.AssertEqual(t, payload, received)
+		}).
+		Test("retries should resend the same compressed body", func(t *testing.T) {
+			var bodies [][]byte
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				data, err := io.ReadAll(r.Body)
+				odize.AssertNoError(t, err)
+				bodies = append(bodies, data)
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+
+			client := New(WithOpts(
+				WithRequestCompression(CompressionGzip, 0),
+				WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond}),
+			))
+			resp, err := client.Post(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, 2, len(bodies))
+			odize.AssertTrue(t, len(bodies[0]) > 0)
+			odize.AssertTrue(t, bytes.Equal(bodies[0], bodies[1]))
+		}).
+		Test("415 fallback should share the overall timeout", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				time.Sleep(150 * time.Millisecond)
+				if r.Header.Get("Content-Encoding") != "" {
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+				}
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionGzip, 0), WithTimeout(200*time.Millisecond)))
+			_, err := client.Post(srv.URL, strings.NewReader(payload), nil)
+			odize.AssertTrue(t, errors.Is(err, ErrTimeout))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("bodies from GetBody should be sent uncompressed on every attempt", func(t *testing.T) {
+			var encodings, bodies []string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				encodings = append(encodings, r.Header.Get("Content-Encoding"))
+				bodies = append(bodies, decodeRequestBody(t, r))
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+
+			client := New(WithOpts(
+				WithRequestCompression(CompressionGzip, 0),
+				WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond}),
+			))
+			resp, err := client.Do(context.Background(), &Request{
+				Method: http.MethodPost,
+				URL:    srv.URL,
+				Body:   strings.NewReader(payload),
+				GetBody: func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(payload)), nil
+				},
+			})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, []string{"", ""}, encodings)
+			odize.AssertEqual(t, []string{payload, payload}, bodies)
+		}).
+		Test("caller provided content-encoding should be sent as is", func(t *testing.T) {
+			var received string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				data, err := io.ReadAll(r.Body)
+				odize.AssertNoError(t, err)
+				received = string(data)
+			})
+
+			client := New(WithOpts(WithRequestCompression(CompressionGzip, 0)))
+			resp, err := client.Post(srv.URL, strings.NewReader("already encoded"), map[string]string{"Content-Encoding": "br"})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, "already encoded", received)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestWithRequestCompression_unsupported(t *testing.T) {
+	options := Options{}
+	err := WithRequestCompression("br", 0)(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrUnsupportedCompression))
+	odize.AssertNil(t, options.RequestCompression)
+}
@@ -0,0 +1,71 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WithConditionalRequests - remember the ETag / Last-Modified of GET responses per URL and send
+// If-None-Match / If-Modified-Since automatically. A 304 Not Modified is returned as a 200 OK backed by the stored body.
+// Uses an in memory store when store is nil. Ignored when a cache is set, the cache revalidates on its own.
+func WithConditionalRequests(store CacheStore) FnOpts {
+	return func(o *Options) error {
+		if store == nil {
+			store = NewMemoryCache(0)
+		}
+		o.ConditionalStore = store
+		return nil
+	}
+}
+
+// IsRevalidated - checks the response was served from a stored body after the server replied 304 Not Modified
+func IsRevalidated(resp *http.Response) bool {
+	return CacheStatusOf(resp) == CacheRevalidated
+}
+
+// doConditional - revalidate GET requests against the stored validators
+func (a *Client) doConditional(ctx context.Context, r *Request) (*http.Response, error) {
+	if r.Method != http.MethodGet {
+		return a.send(ctx, r)
+	}
+
+	reqHeader := requestHeader(r, a.DefaultHeaders)
+	if reqHeader.Get("If-None-Match") != "" || reqHeader.Get("If-Modified-Since") != "" {
+		// the caller handles the conditional request
+		return a.send(ctx, r)
+	}
+
+	key := cacheKey(r)
+	entry, ok := a.ConditionalStore.Get(key)
+	if ok && !entry.matchesVary(reqHeader) {
+		ok = false
+	}
+
+	req := r
+	if ok {
+		conditional := *r
+		conditional.Headers = mergeHeaders(r.Headers, entry.conditionalHeaders())
+		req = &conditional
+	}
+
+	requestTime := time.Now()
+	resp, err := a.send(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	responseTime := time.Now()
+	if ok && resp.StatusCode == http.StatusNotModified {
+		discard(resp)
+		updated := entry.revalidated(resp.Header, requestTime, responseTime)
+		a.ConditionalStore.Set(key, updated)
+		return updated.response(updated.age(responseTime), CacheRevalidated), nil
+	}
+
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return resp, nil
+	}
+
+	return store(a.ConditionalStore, key, resp, reqHeader, requestTime, responseTime, CacheMiss)
+}
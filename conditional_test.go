@@ -0,0 +1,117 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestClient_conditional_requests(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithConditionalRequests(nil)))
+	})
+
+	err := group.
+		Test("etag should be sent and 304 served from the stored body", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Cache-Control", "no-cache")
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertFalse(t, IsRevalidated(resp))
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.StatusOK, resp.StatusCode)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertTrue(t, IsRevalidated(resp))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("last-modified should be sent as if-modified-since", func(t *testing.T) {
+			lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				w.Header().Set("Last-Modified", lastModified)
+				if r.Header.Get("If-Modified-Since") == lastModified {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			resp, err = client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+			odize.AssertTrue(t, IsRevalidated(resp))
+		}).
+		Test("changed response should replace the stored body", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				if count == 1 {
+					w.Header().Set("ETag", `"v1"`)
+					_, _ = w.Write([]byte("hello"))
+					return
+				}
+				w.Header().Set("ETag", `"v2"`)
+				if r.Header.Get("If-None-Match") == `"v2"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = w.Write([]byte("updated"))
+			})
+
+			for _, expected := range []string{"hello", "updated", "updated"} {
+				resp, err := client.Get(srv.URL, nil)
+				odize.AssertNoError(t, err)
+				odize.AssertEqual(t, expected, readBody(t, resp))
+			}
+		}).
+		Test("caller provided validators should pass through", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("ETag", `"v1"`)
+				w.WriteHeader(http.StatusNotModified)
+			})
+
+			resp, err := client.Get(srv.URL, map[string]string{"If-None-Match": `"v1"`})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, http.StatusNotModified, resp.StatusCode)
+			odize.AssertFalse(t, IsRevalidated(resp))
+		}).
+		Test("responses without validators should not be stored", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte("hello"))
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			_, ok := client.ConditionalStore.Get(http.MethodGet + " " + srv.URL)
+			odize.AssertFalse(t, ok)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestWithConditionalRequests_custom_store(t *testing.T) {
+	store := NewMemoryCache(1024)
+	options := WithOpts(WithConditionalRequests(store))
+	odize.AssertEqual(t, store, options.ConditionalStore)
+}
@@ -0,0 +1,729 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidCurl - the curl command line cannot be parsed
+	ErrInvalidCurl = errors.New("invalid curl command")
+	// ErrUnsupportedCurlOption - the curl option has no equivalent request setting
+	ErrUnsupportedCurlOption = errors.New("unsupported curl option")
+)
+
+// MaxCurlBodySize - request bodies larger than this, or of unknown length, are rendered as --data-binary @-
+const MaxCurlBodySize = 1 << 20
+
+// curlRedacted - replaces redacted header values
+const curlRedacted = "REDACTED"
+
+// curlShortOptions - long names of the supported short options
+var curlShortOptions = map[byte]string{
+	'X': "request",
+	'H': "header",
+	'd': "data",
+	'u': "user",
+	'F': "form",
+	'A': "user-agent",
+	'e': "referer",
+	'b': "cookie",
+	'G': "get",
+	'I': "head",
+	'm': "max-time",
+	'o': "output",
+	's': "silent",
+	'S': "show-error",
+	'L': "location",
+	'v': "verbose",
+	'i': "include",
+	'f': "fail",
+}
+
+// curlOptionsWithValue - options followed by a value
+var curlOptionsWithValue = map[string]bool{
+	"request":         true,
+	"header":          true,
+	"data":            true,
+	"data-ascii":      true,
+	"data-binary":     true,
+	"data-raw":        true,
+	"data-urlencode":  true,
+	"json":            true,
+	"user":            true,
+	"form":            true,
+	"form-string":     true,
+	"user-agent":      true,
+	"referer":         true,
+	"cookie":          true,
+	"max-time":        true,
+	"url":             true,
+	"output":          true,
+	"connect-timeout": true,
+}
+
+// curlIgnoredOptions - options affecting curl's output or behaviour the client already provides, e.g. --compressed
+var curlIgnoredOptions = map[string]bool{
+	"compressed":        true,
+	"output":            true,
+	"connect-timeout":   true,
+	"silent":            true,
+	"show-error":        true,
+	"location":          true,
+	"verbose":           true,
+	"include":           true,
+	"fail":              true,
+	"no-progress-meter": true,
+}
+
+// curlCommand - options of a parsed curl command line
+type curlCommand struct {
+	method  string
+	url     string
+	headers map[string]string
+	data    []string
+	json    bool
+	get     bool
+	head    bool
+	form    *Multipart
+	timeout time.Duration
+}
+
+// ParseCurl - convert a curl command line into a request that can be sent with Client.Do.
+//
+// Supports -X, -H, -d / --data, --data-binary, --data-raw, --data-urlencode, --json, -u, -F, --form-string, -A, -e, -b, -G, -I,
+// -m and --url. Quoting and line continuations follow the shell. --compressed and output options such as -s, -L and -o are
+// ignored, the client follows redirects and decodes compressed responses by default. Other options return ErrUnsupportedCurlOption.
+//
+// Example:
+//
+//	req, err := fetch.ParseCurl(`curl -X POST https://api.example.com/users \
+//		-H 'Content-Type: application/json' \
+//		-d '{"name":"alice"}'`)
+//	if err != nil {
+//		// Handle error
+//	}
+//	resp, err := client.Do(ctx, req)
+func ParseCurl(command string) (*Request, error) {
+	args, err := splitCurl(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, fmt.Errorf("%w: must start with curl", ErrInvalidCurl)
+	}
+
+	c := &curlCommand{headers: map[string]string{}}
+	if err = c.parse(args[1:]); err != nil {
+		return nil, err
+	}
+
+	return c.request()
+}
+
+// parse - apply the options and the URL
+func (c *curlCommand) parse(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var names []string
+		var value string
+		hasValue := false
+
+		switch {
+		case strings.HasPrefix(arg, "--") && len(arg) > 2:
+			name, v, ok := strings.Cut(arg[2:], "=")
+			names, value, hasValue = []string{name}, v, ok
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			// short options may be combined, e.g. -sSL, or followed by their value, e.g. -XPOST
+			for j := 1; j < len(arg); j++ {
+				name, ok := curlShortOptions[arg[j]]
+				if !ok {
+					return fmt.Errorf("%w: -%c", ErrUnsupportedCurlOption, arg[j])
+				}
+				names = append(names, name)
+				if curlOptionsWithValue[name] && j < len(arg)-1 {
+					value, hasValue = arg[j+1:], true
+					break
+				}
+			}
+		default:
+			if c.url != "" {
+				return fmt.Errorf("%w: more than one URL", ErrUnsupportedCurlOption)
+			}
+			c.url = arg
+			continue
+		}
+
+		for _, name := range names {
+			if !curlOptionsWithValue[name] {
+				if err := c.flag(name); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !hasValue {
+				if i == len(args)-1 {
+					return fmt.Errorf("%w: --%s requires a value", ErrInvalidCurl, name)
+				}
+				i++
+				value = args[i]
+			}
+
+			if err := c.option(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flag - apply an option without a value
+func (c *curlCommand) flag(name string) error {
+	switch {
+	case name == "get":
+		c.get = true
+	case name == "head":
+		c.head = true
+	case curlIgnoredOptions[name]:
+	default:
+		return fmt.Errorf("%w: --%s", ErrUnsupportedCurlOption, name)
+	}
+
+	return nil
+}
+
+// option - apply an option and its value
+func (c *curlCommand) option(name, value string) error {
+	switch name {
+	case "request":
+		c.method = strings.ToUpper(value)
+	case "url":
+		c.url = value
+	case "header":
+		c.header(value)
+	case "user-agent":
+		c.headers["User-Agent"] = value
+	case "referer":
+		c.headers["Referer"] = value
+	case "cookie":
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("%w: --cookie with a cookie file", ErrUnsupportedCurlOption)
+		}
+		c.headers["Cookie"] = value
+	case "user":
+		if !strings.Contains(value, ":") {
+			value += ":"
+		}
+		c.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(value))
+	case "max-time":
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("%w: --max-time %s", ErrInvalidCurl, value)
+		}
+		c.timeout = time.Duration(seconds * float64(time.Second))
+	case "form", "form-string":
+		return c.formPart(name, value)
+	case "data", "data-ascii", "data-binary", "data-raw", "data-urlencode", "json":
+		return c.dataPart(name, value)
+	default:
+		if !curlIgnoredOptions[name] {
+			return fmt.Errorf("%w: --%s", ErrUnsupportedCurlOption, name)
+		}
+	}
+
+	return nil
+}
+
+// header - "Name: value" sets the header, "Name;" sets an empty value and "Name:" removes it
+func (c *curlCommand) header(value string) {
+	if name, ok := strings.CutSuffix(value, ";"); ok && !strings.Contains(name, ":") {
+		c.headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = ""
+		return
+	}
+
+	name, val, _ := strings.Cut(value, ":")
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	val = strings.TrimSpace(val)
+	if val == "" {
+		delete(c.headers, name)
+		return
+	}
+
+	c.headers[name] = val
+}
+
+// dataPart - add a request body part, parts are joined with &
+func (c *curlCommand) dataPart(name, value string) error {
+	switch name {
+	case "data-raw":
+	case "data-urlencode":
+		encoded, err := curlURLEncode(value)
+		if err != nil {
+			return err
+		}
+		value = encoded
+	default:
+		if path, ok := strings.CutPrefix(value, "@"); ok {
+			content, err := curlReadFile(path)
+			if err != nil {
+				return err
+			}
+			value = string(content)
+			if name == "data" || name == "data-ascii" {
+				// curl strips line breaks from files read with -d
+				value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+			}
+		}
+	}
+
+	if name == "json" {
+		c.json = true
+	}
+	c.data = append(c.data, value)
+
+	return nil
+}
+
+// formPart - add a multipart field, name=value, name=@path for a file or name=<path for a field read from a file
+func (c *curlCommand) formPart(option, value string) error {
+	if c.form == nil {
+		c.form = NewMultipart()
+	}
+
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("%w: --%s %s must be formatted as name=content", ErrInvalidCurl, option, value)
+	}
+
+	if option == "form-string" {
+		c.form.Field(name, content)
+		return nil
+	}
+
+	// the type and filename parameters are not supported, the content type is detected from the file extension
+	switch {
+	case strings.HasPrefix(content, "@"):
+		path, _, _ := strings.Cut(content[1:], ";")
+		c.form.FilePath(name, filepath.Clean(path))
+	case strings.HasPrefix(content, "<"):
+		path, _, _ := strings.Cut(content[1:], ";")
+		data, err := curlReadFile(path)
+		if err != nil {
+			return err
+		}
+		c.form.Field(name, string(data))
+	default:
+		c.form.Field(name, content)
+	}
+
+	return nil
+}
+
+// request - the request described by the options
+func (c *curlCommand) request() (*Request, error) {
+	if c.url == "" {
+		return nil, fmt.Errorf("%w: missing URL", ErrInvalidCurl)
+	}
+
+	if c.form != nil && len(c.data) > 0 {
+		return nil, fmt.Errorf("%w: --form cannot be combined with --data", ErrInvalidCurl)
+	}
+
+	target := c.url
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	var body []byte
+	if len(c.data) > 0 {
+		separator := "&"
+		if c.json {
+			separator = ""
+		}
+		body = []byte(strings.Join(c.data, separator))
+	}
+
+	if c.get && body != nil {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + string(body)
+		body = nil
+	}
+
+	method := c.method
+	if method == "" {
+		switch {
+		case c.head:
+			method = http.MethodHead
+		case c.form != nil, body != nil:
+			method = http.MethodPost
+		default:
+			method = http.MethodGet
+		}
+	}
+
+	if c.form != nil {
+		req, err := c.form.Request(method, target)
+		if err != nil {
+			return nil, err
+		}
+		req.Headers = mergeHeaders(c.headers, req.Headers)
+		req.Timeout = c.timeout
+		return req, nil
+	}
+
+	req := &Request{Method: method, URL: target, Headers: c.headers, Timeout: c.timeout}
+	if body != nil {
+		req.Body = bytes.NewReader(body)
+		contentType, accept := "application/x-www-form-urlencoded", ""
+		if c.json {
+			contentType, accept = "application/json", "application/json"
+		}
+		if _, ok := req.Headers["Content-Type"]; !ok {
+			req.Headers["Content-Type"] = contentType
+		}
+		if _, ok := req.Headers["Accept"]; !ok && accept != "" {
+			req.Headers["Accept"] = accept
+		}
+	}
+
+	return req, nil
+}
+
+// curlURLEncode - --data-urlencode content, =content, name=content, @path or name@path
+func curlURLEncode(value string) (string, error) {
+	if name, content, ok := strings.Cut(value, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+
+	if name, path, ok := strings.Cut(value, "@"); ok {
+		content, err := curlReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			return url.QueryEscape(string(content)), nil
+		}
+		return name + "=" + url.QueryEscape(string(content)), nil
+	}
+
+	return url.QueryEscape(value), nil
+}
+
+// curlReadFile - content of a file referenced by an option, stdin is not supported
+func curlReadFile(path string) ([]byte, error) {
+	if path == "-" {
+		return nil, fmt.Errorf("%w: reading stdin", ErrUnsupportedCurlOption)
+	}
+
+	return os.ReadFile(filepath.Clean(path))
+}
+
+// splitCurl - split a command line into arguments following shell quoting, escaping and line continuations
+func splitCurl(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	for i := 0; i < len(command); i++ {
+		ch := command[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+			continue
+		case ch == '\\':
+			if i+1 < len(command) {
+				i++
+				if command[i] == '\r' && i+1 < len(command) && command[i+1] == '\n' {
+					i++
+				}
+				if command[i] == '\n' {
+					// line continuation
+					continue
+				}
+				current.WriteByte(command[i])
+			}
+		case ch == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated single quote", ErrInvalidCurl)
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+		case ch == '"':
+			end, err := curlDoubleQuoted(command, i+1, &current)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case ch == '$' && i+1 < len(command) && command[i+1] == '\'':
+			end, err := curlANSIQuoted(command, i+2, &current)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		default:
+			current.WriteByte(ch)
+		}
+		inArg = true
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// curlDoubleQuoted - write the double quoted string starting at start, returns the index of the closing quote
+func curlDoubleQuoted(command string, start int, w *strings.Builder) (int, error) {
+	for i := start; i < len(command); i++ {
+		switch ch := command[i]; {
+		case ch == '"':
+			return i, nil
+		case ch == '\\' && i+1 < len(command) && strings.IndexByte("$`\"\\\n", command[i+1]) >= 0:
+			i++
+			if command[i] != '\n' {
+				w.WriteByte(command[i])
+			}
+		default:
+			w.WriteByte(ch)
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated double quote", ErrInvalidCurl)
+}
+
+// curlANSIQuoted - write the $'...' string starting at start, returns the index of the closing quote
+func curlANSIQuoted(command string, start int, w *strings.Builder) (int, error) {
+	escapes := map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', 'a': '\a', 'b': '\b', 'f': '\f', 'v': '\v', 'e': 0x1b, '\\': '\\', '\'': '\'', '"': '"'}
+
+	for i := start; i < len(command); i++ {
+		ch := command[i]
+		switch {
+		case ch == '\'':
+			return i, nil
+		case ch == '\\' && i+1 < len(command):
+			i++
+			if escaped, ok := escapes[command[i]]; ok {
+				w.WriteByte(escaped)
+				continue
+			}
+			if command[i] == 'x' && i+2 < len(command) {
+				if b, err := strconv.ParseUint(command[i+1:i+3], 16, 8); err == nil {
+					w.WriteByte(byte(b))
+					i += 2
+					continue
+				}
+			}
+			w.WriteByte('\\')
+			w.WriteByte(command[i])
+		default:
+			w.WriteByte(ch)
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated $' quote", ErrInvalidCurl)
+}
+
+// CurlCommand - render the request as an equivalent curl command, e.g. for debugging logs.
+// Values of the redacted headers are replaced with REDACTED. Bodies larger than MaxCurlBodySize, of unknown length
+// or that cannot be read again without consuming the request are rendered as --data-binary @-,
+// other bodies are read once more with req.GetBody.
+// An Accept-Encoding header is rendered as --compressed as the client decodes compressed responses.
+func CurlCommand(req *http.Request, redactHeaders ...string) (string, error) {
+	body, streamed, err := curlBody(req)
+	if err != nil {
+		return "", err
+	}
+
+	return renderCurl(req, body, streamed, redactHeaders), nil
+}
+
+// renderCurl - render the request with the copied body, or @- when streamed
+func renderCurl(req *http.Request, body []byte, streamed bool, redactHeaders []string) string {
+
+	args := []string{"curl"}
+	hasBody := streamed || body != nil
+	switch {
+	case req.Method == http.MethodHead:
+		args = append(args, "--head")
+	case (req.Method == "" || req.Method == http.MethodGet) && !hasBody:
+	case req.Method == http.MethodPost && hasBody:
+	default:
+		args = append(args, "-X", curlQuote(req.Method))
+	}
+
+	redacted := map[string]bool{}
+	for _, name := range redactHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	if req.Host != "" && req.URL != nil && req.Host != req.URL.Host {
+		args = append(args, "-H", curlQuote("Host: "+req.Host))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length":
+			continue
+		case "Accept-Encoding":
+			args = append(args, "--compressed")
+			continue
+		}
+
+		for _, value := range req.Header[name] {
+			if redacted[http.CanonicalHeaderKey(name)] {
+				value = curlRedacted
+			}
+			args = append(args, "-H", curlQuote(name+": "+value))
+		}
+	}
+
+	switch {
+	case streamed:
+		args = append(args, "--data-binary", "@-")
+	case body != nil:
+		args = append(args, "--data-binary", curlQuote(string(body)))
+	}
+
+	if req.URL != nil {
+		args = append(args, curlQuote(req.URL.String()))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// curlBody - a copy of the request body, streamed when it cannot be copied
+func curlBody(req *http.Request) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false, nil
+	}
+
+	if req.GetBody == nil || req.ContentLength <= 0 || req.ContentLength > MaxCurlBodySize {
+		return nil, true, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false, err
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return content, false, nil
+}
+
+var curlSafe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// curlQuote - quote the value for the shell, values with control characters or invalid UTF-8 use $'...'
+func curlQuote(value string) string {
+	if curlSafe.MatchString(value) {
+		return value
+	}
+
+	printable := utf8.ValidString(value)
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			printable = false
+			break
+		}
+	}
+
+	if printable {
+		return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	}
+
+	var quoted strings.Builder
+	quoted.WriteString("$'")
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case ch == '\n':
+			quoted.WriteString(`\n`)
+		case ch == '\r':
+			quoted.WriteString(`\r`)
+		case ch == '\t':
+			quoted.WriteString(`\t`)
+		case ch == '\\' || ch == '\'':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(ch)
+		case ch < 0x20 || ch == 0x7f:
+			_, _ = fmt.Fprintf(&quoted, `\x%02x`, ch)
+		case ch >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(value[i:])
+			if r == utf8.RuneError && size <= 1 {
+				_, _ = fmt.Fprintf(&quoted, `\x%02x`, ch)
+				continue
+			}
+			quoted.WriteString(value[i : i+size])
+			i += size - 1
+		default:
+			quoted.WriteByte(ch)
+		}
+	}
+	quoted.WriteByte('\'')
+
+	return quoted.String()
+}
+
+// WithCurlLog - receive every attempt rendered as a curl command after headers are added and the request is signed.
+// Values of the redacted headers, e.g. Authorization, are replaced with REDACTED.
+// Only bodies held in memory are rendered, bodies streamed from Request.GetBody such as multipart files are rendered as --data-binary @-.
+func WithCurlLog(fn func(command string), redactHeaders ...string) FnOpts {
+	return func(o *Options) error {
+		o.CurlLog = fn
+		o.CurlLogRedact = redactHeaders
+		return nil
+	}
+}
+
+// logCurl - pass the attempt to the curl log. Streamed bodies are not read again, the sources are only opened for the attempt
+func (a *Client) logCurl(req *http.Request, streamed bool) {
+	if a.CurlLog == nil {
+		return
+	}
+
+	if streamed && req.Body != nil && req.Body != http.NoBody {
+		a.CurlLog(renderCurl(req, nil, true, a.CurlLogRedact))
+		return
+	}
+
+	command, err := CurlCommand(req, a.CurlLogRedact...)
+	if err != nil {
+		command = fmt.Sprintf("# %s %s: %s", req.Method, req.URL, err)
+	}
+
+	a.CurlLog(command)
+}
@@ -0,0 +1,278 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// signerFunc - signs requests with the function
+type signerFunc func(req *http.Request) error
+
+func (f signerFunc) Sign(req *http.Request) error {
+	return f(req)
+}
+
+func requestBody(t *testing.T, req *Request) string {
+	t.Helper()
+
+	if req.Body == nil {
+		return ""
+	}
+
+	data, err := io.ReadAll(req.Body)
+	odize.AssertNoError(t, err)
+	return string(data)
+}
+
+func TestParseCurl(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should parse a pasted multi line command", func(t *testing.T) {
+			req, err := ParseCurl(`curl -X POST "https://api.example.com/users" \
+				-H 'Content-Type: application/json' \
+				-H "Authorization: Bearer \"abc\"" \
+				-d '{"name":"it'\''s alice"}' --compressed -sSL`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPost, req.Method)
+			odize.AssertEqual(t, "https://api.example.com/users", req.URL)
+			odize.AssertEqual(t, map[string]string{"Content-Type": "application/json", "Authorization": `Bearer "abc"`}, req.Headers)
+			odize.AssertEqual(t, `{"name":"it's alice"}`, requestBody(t, req))
+		}).
+		Test("data should default to a form post and join parts", func(t *testing.T) {
+			req, err := ParseCurl(`curl example.com/search -d a=1 --data-urlencode 'q=hello world' --data-raw @literal`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPost, req.Method)
+			odize.AssertEqual(t, "http://example.com/search", req.URL)
+			odize.AssertEqual(t, "application/x-www-form-urlencoded", req.Headers["Content-Type"])
+			odize.AssertEqual(t, "a=1&q=hello+world&@literal", requestBody(t, req))
+		}).
+		Test("get should move the data to the query", func(t *testing.T) {
+			req, err := ParseCurl(`curl -G 'https://example.com/search?page=2' -d q=go -I`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodHead, req.Method)
+			odize.AssertEqual(t, "https://example.com/search?page=2&q=go", req.URL)
+			odize.AssertNil(t, req.Body)
+		}).
+		Test("should set auth, cookie, user agent, referer and timeout headers", func(t *testing.T) {
+			req, err := ParseCurl(`curl -XPUT -u alice:secret -b 'a=1; b=2' -A agent/1.0 --referer=https://example.com -m 1.5 --url https://example.com/items/1 -H 'X-Empty;' -H 'x-removed: 1' -H 'X-Removed:'`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPut, req.Method)
+			odize.AssertEqual(t, "https://example.com/items/1", req.URL)
+			odize.AssertEqual(t, map[string]string{
+				"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret")),
+				"Cookie":        "a=1; b=2",
+				"User-Agent":    "agent/1.0",
+				"Referer":       "https://example.com",
+				"X-Empty":       "",
+			}, req.Headers)
+			odize.AssertEqual(t, 1500*time.Millisecond, req.Timeout)
+		}).
+		Test("json should set the content type and accept headers", func(t *testing.T) {
+			req, err := ParseCurl(`curl --json '{"a":1}' https://example.com`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPost, req.Method)
+			odize.AssertEqual(t, "application/json", req.Headers["Content-Type"])
+			odize.AssertEqual(t, "application/json", req.Headers["Accept"])
+			odize.AssertEqual(t, `{"a":1}`, requestBody(t, req))
+		}).
+		Test("data files should be read, -d strips line breaks", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "body.txt")
+			odize.AssertNoError(t, os.WriteFile(path, []byte("a=1\nb=2\n"), 0o600))
+
+			req, err := ParseCurl(`curl -d @` + path + ` https://example.com`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "a=1b=2", requestBody(t, req))
+
+			req, err = ParseCurl(`curl --data-binary @` + path + ` https://example.com`)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "a=1\nb=2\n", requestBody(t, req))
+		}).
+		Test("form should send a retryable multipart body", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.csv")
+			odize.AssertNoError(t, os.WriteFile(path, []byte("a,b\n1,2\n"), 0o600))
+
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				file, header, err := r.FormFile("report")
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				defer file.Close()
+				content, _ := io.ReadAll(file)
+				_, _ = w.Write([]byte(r.FormValue("description") + "|" + header.Filename + "|" + string(content)))
+			})
+
+			req, err := ParseCurl(`curl -F 'description=quarterly report' -F report=@` + path + `;type=text/csv ` + srv.URL)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPost, req.Method)
+			odize.AssertTrue(t, strings.HasPrefix(req.Headers["Content-Type"], "multipart/form-data; boundary="))
+
+			client := New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+			resp, err := client.Do(context.Background(), req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "quarterly report|report.csv|a,b\n1,2\n", readBody(t, resp))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("invalid commands should return errors", func(t *testing.T) {
+			_, err := ParseCurl(`curl -k https://example.com`)
+			odize.AssertTrue(t, errors.Is(err, ErrUnsupportedCurlOption))
+
+			_, err = ParseCurl(`curl --proxy http://proxy https://example.com`)
+			odize.AssertTrue(t, errors.Is(err, ErrUnsupportedCurlOption))
+
+			_, err = ParseCurl(`curl 'https://example.com`)
+			odize.AssertTrue(t, errors.Is(err, ErrInvalidCurl))
+
+			_, err = ParseCurl(`wget https://example.com`)
+			odize.AssertTrue(t, errors.Is(err, ErrInvalidCurl))
+
+			_, err = ParseCurl(`curl -H`)
+			odize.AssertTrue(t, errors.Is(err, ErrInvalidCurl))
+
+			_, err = ParseCurl(`curl -s`)
+			odize.AssertTrue(t, errors.Is(err, ErrInvalidCurl))
+
+			_, err = ParseCurl(`curl -F a=1 -d b=2 https://example.com`)
+			odize.AssertTrue(t, errors.Is(err, ErrInvalidCurl))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestCurlCommand(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should render the method, sorted headers, body and URL", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "https://example.com/items/1?a=b&c=d", strings.NewReader(`{"name":"it's"}`))
+			odize.AssertNoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer abc")
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			command, err := CurlCommand(req, "authorization")
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `curl -X PUT --compressed -H 'Authorization: REDACTED' -H 'Content-Type: application/json' --data-binary '{"name":"it'\''s"}' 'https://example.com/items/1?a=b&c=d'`, command)
+		}).
+		Test("should omit the default methods", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+			odize.AssertNoError(t, err)
+			command, err := CurlCommand(req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "curl https://example.com/", command)
+
+			req, err = http.NewRequest(http.MethodPost, "https://example.com/", strings.NewReader("a=1"))
+			odize.AssertNoError(t, err)
+			command, err = CurlCommand(req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "curl --data-binary a=1 https://example.com/", command)
+
+			req, err = http.NewRequest(http.MethodHead, "https://example.com/", nil)
+			odize.AssertNoError(t, err)
+			command, err = CurlCommand(req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "curl --head https://example.com/", command)
+		}).
+		Test("binary and streamed bodies should stay on one line", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "https://example.com/", bytes.NewReader([]byte("line\n\x00\xff'é")))
+			odize.AssertNoError(t, err)
+			command, err := CurlCommand(req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `curl --data-binary $'line\n\x00\xff\'é' https://example.com/`, command)
+
+			req, err = http.NewRequest(http.MethodPost, "https://example.com/", io.NopCloser(strings.NewReader("stream")))
+			odize.AssertNoError(t, err)
+			command, err = CurlCommand(req)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "curl --data-binary @- https://example.com/", command)
+		}).
+		Test("rendered commands should parse back to the same request", func(t *testing.T) {
+			body := "first\nsecond\t\x01 'quoted' \"double\" $HOME"
+			req, err := http.NewRequest(http.MethodPatch, "https://example.com/items?q=a b", strings.NewReader(body))
+			odize.AssertNoError(t, err)
+			req.Header.Set("X-Value", "it's $HOME")
+
+			command, err := CurlCommand(req)
+			odize.AssertNoError(t, err)
+
+			parsed, err := ParseCurl(command)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.MethodPatch, parsed.Method)
+			odize.AssertEqual(t, req.URL.String(), parsed.URL)
+			odize.AssertEqual(t, "it's $HOME", parsed.Headers["X-Value"])
+			odize.AssertEqual(t, body, requestBody(t, parsed))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestClient_curlLog(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should log every attempt with default headers and a redacted signature", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, count int32) {
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+
+			var commands []string
+			client := New(WithOpts(
+				WithHeaders(map[string]string{"X-Default": "yes"}),
+				WithSigner(signerFunc(func(req *http.Request) error {
+					req.Header.Set("Authorization", "signed")
+					return nil
+				})),
+				WithCurlLog(func(command string) { commands = append(commands, command) }, "Authorization"),
+				WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond}),
+			))
+
+			resp, err := client.Post(srv.URL, strings.NewReader("a=1"), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			expected := "curl --compressed -H 'Authorization: REDACTED' -H 'X-Default: yes' --data-binary a=1 " + srv.URL
+			odize.AssertEqual(t, []string{expected, expected}, commands)
+		}).
+		Test("streamed bodies should not be read again for the log", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+
+			var commands []string
+			client := New(WithOpts(WithCurlLog(func(command string) { commands = append(commands, command) })))
+
+			var opened int
+			resp, err := client.Do(context.Background(), &Request{
+				Method: http.MethodPut,
+				URL:    srv.URL,
+				GetBody: func() (io.ReadCloser, error) {
+					opened++
+					return io.NopCloser(strings.NewReader("streamed")), nil
+				},
+				ContentLength: 8,
+			})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			odize.AssertEqual(t, 1, opened)
+			odize.AssertEqual(t, []string{"curl -X PUT --compressed --data-binary @- " + srv.URL}, commands)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
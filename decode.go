@@ -0,0 +1,122 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+var (
+	// ErrNotJSONArray - the response body is not a JSON array
+	ErrNotJSONArray = errors.New("response body is not a JSON array")
+	// ErrNoResponseBody - the response or its body is nil
+	ErrNoResponseBody = errors.New("response has no body")
+)
+
+// DecodeNDJSON - decode a newline delimited JSON response body one item at a time, without buffering the whole body.
+// The body is closed once iteration ends, including when the loop is stopped early. Iteration stops after yielding an error.
+//
+// Example:
+//
+//	resp, err := client.GetCtx(ctx, url, nil)
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	for record, err := range fetch.DecodeNDJSON[Record](resp) {
+//		if err != nil {
+//			// Handle error
+//			break
+//		}
+//		fmt.Println(record)
+//	}
+func DecodeNDJSON[T any](resp *http.Response) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		decoder, body, err := jsonDecoder(resp)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		for {
+			var item T
+			err = decoder.Decode(&item)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(item, err)
+				return
+			}
+
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DecodeJSONArray - decode the items of a JSON array response body one at a time, without buffering the whole body.
+// The body is closed once iteration ends, including when the loop is stopped early. Iteration stops after yielding an error.
+//
+// Example:
+//
+//	for record, err := range fetch.DecodeJSONArray[Record](resp) {
+//		if err != nil {
+//			// Handle error
+//			break
+//		}
+//		fmt.Println(record)
+//	}
+func DecodeJSONArray[T any](resp *http.Response) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		decoder, body, err := jsonDecoder(resp)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		token, err := decoder.Token()
+		if err != nil {
+			yield(zero, fmt.Errorf("%w: %w", ErrNotJSONArray, err))
+			return
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			yield(zero, fmt.Errorf("%w: starts with %v", ErrNotJSONArray, token))
+			return
+		}
+
+		for decoder.More() {
+			var item T
+			if err = decoder.Decode(&item); err != nil {
+				yield(item, err)
+				return
+			}
+
+			if !yield(item, nil) {
+				return
+			}
+		}
+
+		if _, err = decoder.Token(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// jsonDecoder - streaming decoder over the response body
+func jsonDecoder(resp *http.Response) (*json.Decoder, io.ReadCloser, error) {
+	if resp == nil || resp.Body == nil {
+		return nil, nil, ErrNoResponseBody
+	}
+
+	return json.NewDecoder(resp.Body), resp.Body, nil
+}
@@ -0,0 +1,147 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+type decodeRecord struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// trackingBody - body recording whether it was closed
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func responseWithBody(body string) (*http.Response, *trackingBody) {
+	tracking := &trackingBody{Reader: strings.NewReader(body)}
+	return &http.Response{StatusCode: http.StatusOK, Body: tracking}, tracking
+}
+
+func TestDecodeNDJSON(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should yield every record and close the body", func(t *testing.T) {
+			resp, body := responseWithBody("{\"id\":1,\"name\":\"a\"}\n\n{\"id\":2,\"name\":\"b\"}\n")
+
+			var records []decodeRecord
+			for record, err := range DecodeNDJSON[decodeRecord](resp) {
+				odize.AssertNoError(t, err)
+				records = append(records, record)
+			}
+
+			odize.AssertEqual(t, []decodeRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, records)
+			odize.AssertTrue(t, body.closed)
+		}).
+		Test("breaking early should close the body", func(t *testing.T) {
+			resp, body := responseWithBody("{\"id\":1}\n{\"id\":2}\n")
+
+			for record := range DecodeNDJSON[decodeRecord](resp) {
+				odize.AssertEqual(t, 1, record.ID)
+				break
+			}
+			odize.AssertTrue(t, body.closed)
+		}).
+		Test("malformed record should yield an error and stop", func(t *testing.T) {
+			resp, _ := responseWithBody("{\"id\":1}\n{\"id\":\n")
+
+			var errs []error
+			count := 0
+			for _, err := range DecodeNDJSON[decodeRecord](resp) {
+				count++
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+			odize.AssertEqual(t, 2, count)
+			odize.AssertEqual(t, 1, len(errs))
+		}).
+		Test("missing body should yield an error", func(t *testing.T) {
+			for _, err := range DecodeNDJSON[decodeRecord](nil) {
+				odize.AssertTrue(t, errors.Is(err, ErrNoResponseBody))
+			}
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestDecodeJSONArray(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should yield every item and close the body", func(t *testing.T) {
+			resp, body := responseWithBody(` [ {"id":1,"name":"a"}, {"id":2,"name":"b"} ] `)
+
+			var records []decodeRecord
+			for record, err := range DecodeJSONArray[decodeRecord](resp) {
+				odize.AssertNoError(t, err)
+				records = append(records, record)
+			}
+
+			odize.AssertEqual(t, []decodeRecord{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, records)
+			odize.AssertTrue(t, body.closed)
+		}).
+		Test("empty array should yield nothing", func(t *testing.T) {
+			resp, _ := responseWithBody(`[]`)
+
+			for range DecodeJSONArray[decodeRecord](resp) {
+				t.Fatal("expected no items")
+			}
+		}).
+		Test("breaking early should close the body", func(t *testing.T) {
+			resp, body := responseWithBody(`[{"id":1},{"id":2}]`)
+
+			for record := range DecodeJSONArray[decodeRecord](resp) {
+				odize.AssertEqual(t, 1, record.ID)
+				break
+			}
+			odize.AssertTrue(t, body.closed)
+		}).
+		Test("object body should yield ErrNotJSONArray", func(t *testing.T) {
+			resp, _ := responseWithBody(`{"id":1}`)
+
+			for _, err := range DecodeJSONArray[decodeRecord](resp) {
+				odize.AssertTrue(t, errors.Is(err, ErrNotJSONArray))
+			}
+		}).
+		Test("truncated array should yield an error", func(t *testing.T) {
+			resp, _ := responseWithBody(`[{"id":1},{"id":2}`)
+
+			var last error
+			for _, err := range DecodeJSONArray[decodeRecord](resp) {
+				last = err
+			}
+			odize.AssertError(t, last)
+		}).
+		Test("should decode a streamed server response", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte(`[1,2,3]`))
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+
+			sum := 0
+			for n, err := range DecodeJSONArray[int](resp) {
+				odize.AssertNoError(t, err)
+				sum += n
+			}
+			odize.AssertEqual(t, 6, sum)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
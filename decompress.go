@@ -0,0 +1,237 @@
+package fetch
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding - content codings decoded by the client
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// DefaultMaxDecompressedSize - maximum decompressed response body size when none is provided, 100MB
+const DefaultMaxDecompressedSize int64 = 100 << 20
+
+var (
+	// ErrDecompressedSizeExceeded - the decompressed response body is larger than the configured maximum
+	ErrDecompressedSizeExceeded = errors.New("decompressed size exceeded")
+	// ErrInvalidDecompressedSize - the maximum decompressed size is negative
+	ErrInvalidDecompressedSize = errors.New("invalid maximum decompressed size")
+)
+
+// WithoutDecompression - keep response bodies as sent by the server, Accept-Encoding is not added and Content-Encoding is left in place
+func WithoutDecompression() FnOpts {
+	return func(o *Options) error {
+		o.DisableDecompression = true
+		return nil
+	}
+}
+
+// WithMaxDecompressedSize - fail reading a compressed response body once it decompresses to more than size bytes,
+// 0 uses DefaultMaxDecompressedSize. Use math.MaxInt64 to lift the limit
+func WithMaxDecompressedSize(size int64) FnOpts {
+	return func(o *Options) error {
+		if size < 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidDecompressedSize, size)
+		}
+		o.MaxDecompressedSize = size
+		return nil
+	}
+}
+
+// negotiateEncoding - advertise the supported content codings unless the caller set Accept-Encoding
+func (a *Client) negotiateEncoding(req *http.Request) {
+	if a.DisableDecompression || req.Header.Get("Accept-Encoding") != "" {
+		return
+	}
+
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+}
+
+// decompress - transparently decode the response body according to its Content-Encoding.
+// Bodies with unsupported codings are left untouched.
+func (a *Client) decompress(resp *http.Response) {
+	if a.DisableDecompression || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return
+	}
+
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return
+	}
+
+	codings := contentCodings(resp.Header.Get("Content-Encoding"))
+	if len(codings) == 0 {
+		return
+	}
+
+	for _, coding := range codings {
+		if !isSupportedCoding(coding) {
+			return
+		}
+	}
+
+	maxSize := a.MaxDecompressedSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+
+	resp.Body = &decodingReader{
+		body:    resp.Body,
+		codings: codings,
+		max:     maxSize,
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// contentCodings - codings in the order they were applied, identity is dropped
+func contentCodings(header string) []string {
+	var codings []string
+	for _, coding := range strings.Split(header, ",") {
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		codings = append(codings, coding)
+	}
+
+	return codings
+}
+
+// isSupportedCoding - coding can be decoded
+func isSupportedCoding(coding string) bool {
+	switch coding {
+	case "gzip", "x-gzip", "deflate", "br", "zstd":
+		return true
+	default:
+		return false
+	}
+}
+
+// decodingReader - decodes the body on first read, so empty or unread bodies never fail
+type decodingReader struct {
+	body    io.ReadCloser
+	codings []string
+	max     int64
+	read    int64
+	reader  io.Reader
+	closers []io.Closer
+	err     error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.reader == nil {
+		if d.err = d.init(); d.err != nil {
+			return 0, d.err
+		}
+	}
+
+	// read one byte past the limit to tell a body of exactly max bytes from a larger one,
+	// remaining is below len(p) when truncating, so remaining+1 cannot overflow
+	if remaining := d.max - d.read; d.max > 0 && int64(len(p)) > remaining {
+		p = p[:remaining+1]
+	}
+
+	n, err := d.reader.Read(p)
+	d.read += int64(n)
+	if d.max > 0 && d.read > d.max {
+		d.err = fmt.Errorf("%w: more than %d bytes", ErrDecompressedSizeExceeded, d.max)
+		return n - int(d.read-d.max), d.err
+	}
+
+	return n, err
+}
+
+func (d *decodingReader) Close() error {
+	for _, closer := range d.closers {
+		_ = closer.Close()
+	}
+
+	return d.body.Close()
+}
+
+// init - build the decoders, the last applied coding is decoded first
+func (d *decodingReader) init() error {
+	var reader io.Reader = d.body
+	for i := len(d.codings) - 1; i >= 0; i-- {
+		decoder, err := d.decoder(d.codings[i], reader)
+		if errors.Is(err, io.EOF) {
+			// empty body
+			decoder, err = http.NoBody, nil
+		}
+		if err != nil {
+			return fmt.Errorf("decode %s body: %w", d.codings[i], err)
+		}
+		reader = decoder
+	}
+
+	d.reader = reader
+	return nil
+}
+
+// decoder - decoder for a single coding
+func (d *decodingReader) decoder(coding string, r io.Reader) (io.Reader, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		d.closers = append(d.closers, reader)
+		return reader, nil
+	case "deflate":
+		return d.deflate(r)
+	case "br":
+		return brotli.NewReader(r), nil
+	case "zstd":
+		reader, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		d.closers = append(d.closers, reader.IOReadCloser())
+		return reader, nil
+	default:
+		return nil, fmt.Errorf("unsupported content coding %q", coding)
+	}
+}
+
+// deflate - HTTP deflate is zlib wrapped, but some servers send raw deflate
+func (d *decodingReader) deflate(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		reader, err := zlib.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		d.closers = append(d.closers, reader)
+		return reader, nil
+	}
+
+	reader := flate.NewReader(buffered)
+	d.closers = append(d.closers, reader)
+	return reader, nil
+}
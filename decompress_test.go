@@ -0,0 +1,213 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/code-gorilla-au/odize"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encode - compress data with the coding
+func encode(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch coding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		odize.AssertNoError(t, err)
+		writer = w
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "zstd":
+		w, err := zstd.NewWriter(&buf)
+		odize.AssertNoError(t, err)
+		writer = w
+	default:
+		t.Fatalf("unknown coding %s", coding)
+	}
+
+	_, err := writer.Write(data)
+	odize.AssertNoError(t, err)
+	odize.AssertNoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestClient_decompression(t *testing.T) {
+	payload := []byte(strings.Repeat("hello world ", 100))
+
+	for _, coding := range []string{"gzip", "deflate", "br", "zstd"} {
+		t.Run(coding, func(t *testing.T) {
+			var accepted string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				accepted = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", coding)
+				_, _ = w.Write(encode(t, coding, payload))
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+			odize.AssertEqual(t, acceptEncoding, accepted)
+			odize.AssertEqual(t, "", resp.Header.Get("Content-Encoding"))
+			odize.AssertTrue(t, resp.Uncompressed)
+		})
+	}
+}
+
+func TestClient_decompression_options(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	payload := []byte(strings.Repeat("hello world ", 100))
+
+	err := group.
+		Test("caller provided accept-encoding should still be decoded", func(t *testing.T) {
+			var accepted string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				accepted = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(encode(t, "gzip", payload))
+			})
+
+			client := New(WithOpts(WithHeaders(map[string]string{"Accept-Encoding": "gzip"})))
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "gzip", accepted)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("raw deflate should be decoded", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "deflate")
+				_, _ = w.Write(encode(t, "raw-deflate", payload))
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("stacked codings should be decoded in reverse order", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip, br")
+				_, _ = w.Write(encode(t, "br", encode(t, "gzip", payload)))
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("unsupported coding should be left untouched", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "compress")
+				_, _ = w.Write([]byte("raw"))
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "compress", resp.Header.Get("Content-Encoding"))
+			odize.AssertEqual(t, "raw", readBody(t, resp))
+		}).
+		Test("without decompression should keep the raw body", func(t *testing.T) {
+			compressed := encode(t, "gzip", payload)
+			var accepted string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				accepted = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(compressed)
+			})
+
+			resp, err := New(WithOpts(WithoutDecompression())).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "", accepted)
+			odize.AssertEqual(t, "gzip", resp.Header.Get("Content-Encoding"))
+			odize.AssertEqual(t, string(compressed), readBody(t, resp))
+		}).
+		Test("body over the decompressed limit should fail to read", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(encode(t, "gzip", bytes.Repeat([]byte{0}, 1<<20)))
+			})
+
+			resp, err := New(WithOpts(WithMaxDecompressedSize(1024))).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+
+			data, err := io.ReadAll(resp.Body)
+			odize.AssertTrue(t, errors.Is(err, ErrDecompressedSizeExceeded))
+			odize.AssertEqual(t, 1024, len(data))
+		}).
+		Test("body at the decompressed limit should be read", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "zstd")
+				_, _ = w.Write(encode(t, "zstd", payload))
+			})
+
+			resp, err := New(WithOpts(WithMaxDecompressedSize(int64(len(payload))))).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("maximum int64 limit should read the whole body", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(encode(t, "gzip", payload))
+			})
+
+			resp, err := New(WithOpts(WithMaxDecompressedSize(math.MaxInt64))).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("empty compressed body should not fail", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			resp, err := New(nil).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "", readBody(t, resp))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestWithMaxDecompressedSize_negative(t *testing.T) {
+	options := Options{}
+	err := WithMaxDecompressedSize(-1)(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrInvalidDecompressedSize))
+}
+
+func TestClient_decompress_default_limit(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:       io.NopCloser(bytes.NewReader(encode(t, "gzip", []byte("data")))),
+	}
+
+	New(nil).decompress(resp)
+
+	reader, ok := resp.Body.(*decodingReader)
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, DefaultMaxDecompressedSize, reader.max)
+}
+
+func TestDecodingReader_empty_body(t *testing.T) {
+	reader := &decodingReader{body: io.NopCloser(strings.NewReader("")), codings: []string{"gzip"}}
+	data, err := io.ReadAll(reader)
+	odize.AssertNoError(t, err)
+	odize.AssertEqual(t, 0, len(data))
+	odize.AssertNoError(t, reader.Close())
+}
@@ -0,0 +1,323 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrChecksumMismatch - the downloaded file does not match the expected SHA-256 checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSizeMismatch - the downloaded file does not match the expected size
+	ErrSizeMismatch = errors.New("size mismatch")
+	// errUnexpectedRange - the server replied with a range other than the one requested, the download restarts
+	errUnexpectedRange = errors.New("unexpected content range")
+)
+
+// DownloadOptions - configuration for Client.Download
+type DownloadOptions struct {
+	// Expected SHA-256 checksum, hex encoded
+	SHA256 string
+	// Headers added to each request
+	Headers map[string]string
+}
+
+// DownloadOpts - configure a download
+type DownloadOpts func(o *DownloadOptions)
+
+// WithSHA256 - verify the downloaded file against a hex encoded SHA-256 checksum
+func WithSHA256(checksum string) DownloadOpts {
+	return func(o *DownloadOptions) {
+		o.SHA256 = strings.ToLower(checksum)
+	}
+}
+
+// WithDownloadHeaders - add headers to each download request
+func WithDownloadHeaders(headers map[string]string) DownloadOpts {
+	return func(o *DownloadOptions) {
+		o.Headers = headers
+	}
+}
+
+// DownloadResult - a completed download
+type DownloadResult struct {
+	// Destination path
+	Path string
+	// Size in bytes
+	Size int64
+	// Hex encoded SHA-256 checksum of the file
+	SHA256 string
+	// Number of times the download was resumed or restarted
+	Resumes int
+}
+
+// Download - download url to the dst file. The body is written to a temporary file next to dst, which is renamed to dst once complete and verified.
+// Dropped connections and recoverable errors resume the download with Range / If-Range, waiting for the retry strategy backoff.
+// Consecutive failed resumes are bounded by the retry strategy, a resume that receives data resets the count. Without a retry strategy the download is not resumed.
+// The size is verified against Content-Length, the checksum against WithSHA256 and the Digest / Content-Digest SHA-256 headers.
+//
+// Example:
+//
+//	result, err := client.Download(ctx, url, "/tmp/artifact.tar.gz", fetch.WithSHA256(checksum))
+//	if err != nil {
+//		// Handle error
+//	}
+//	fmt.Println(result.Size, result.SHA256)
+func (a *Client) Download(ctx context.Context, url, dst string, opts ...DownloadOpts) (*DownloadResult, error) {
+	var options DownloadOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	d := &download{
+		client:  a,
+		url:     url,
+		headers: options.Headers,
+		file:    file,
+		hash:    sha256.New(),
+		total:   -1,
+	}
+
+	failures := 0
+	resumes := 0
+	for {
+		progressed, err := d.fetch(ctx)
+		if err == nil {
+			break
+		}
+
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		if progressed {
+			failures = 0
+		}
+
+		if !isReconnectable(err) || failures >= len(a.RetryStrategy) {
+			return nil, err
+		}
+
+		if waitErr := wait(ctx, a.RetryStrategy[failures]); waitErr != nil {
+			return nil, context.Cause(ctx)
+		}
+		failures++
+		resumes++
+	}
+
+	if err = d.verify(options.SHA256); err != nil {
+		return nil, err
+	}
+
+	if err = file.Sync(); err != nil {
+		return nil, err
+	}
+
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
+
+	if err = os.Rename(file.Name(), dst); err != nil {
+		return nil, err
+	}
+
+	return &DownloadResult{
+		Path:    dst,
+		Size:    d.written,
+		SHA256:  hex.EncodeToString(d.hash.Sum(nil)),
+		Resumes: resumes,
+	}, nil
+}
+
+// download - state of a download across resumes
+type download struct {
+	client  *Client
+	url     string
+	headers map[string]string
+	file    *os.File
+	hash    hash.Hash
+	written int64
+	// expected size, -1 when unknown
+	total int64
+	// strong ETag or Last-Modified of the representation, used with If-Range
+	validator string
+	// SHA-256 sent by the server in Digest / Content-Digest
+	digest []byte
+}
+
+// fetch - request the remaining bytes and append them to the file, returns whether any bytes were received
+func (d *download) fetch(ctx context.Context) (bool, error) {
+	// ranges apply to the encoded body, so ask for the identity encoding
+	headers := mergeHeaders(d.headers, map[string]string{"Accept-Encoding": "identity"})
+
+	resuming := d.written > 0 && d.validator != ""
+	if d.written > 0 && !resuming {
+		if err := d.reset(); err != nil {
+			return false, err
+		}
+	}
+	if resuming {
+		headers["Range"] = fmt.Sprintf("bytes=%d-", d.written)
+		headers["If-Range"] = d.validator
+	}
+
+	resp, err := d.client.sendOnce(ctx, &Request{Method: http.MethodGet, URL: d.url, Headers: headers, stream: true})
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			discard(resp)
+		}
+
+		if resuming && resp != nil && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && d.written == d.total {
+			// already complete
+			return false, nil
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPartialContent {
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !resuming || !ok || start != d.written {
+			return false, errors.Join(errUnexpectedRange, d.reset())
+		}
+		if total >= 0 {
+			d.total = total
+		}
+		if digest := digestOf(resp.Header.Get("Digest"), ""); digest != nil {
+			d.digest = digest
+		}
+	} else {
+		if err = d.reset(); err != nil {
+			return false, err
+		}
+		d.total = resp.ContentLength
+		d.validator = validatorOf(resp.Header)
+		d.digest = digestOf(resp.Header.Get("Digest"), resp.Header.Get("Content-Digest"))
+	}
+
+	n, err := io.Copy(io.MultiWriter(d.file, d.hash), resp.Body)
+	d.written += n
+	if err == nil && d.total >= 0 && d.written < d.total {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n > 0, err
+}
+
+// reset - discard the bytes written so far
+func (d *download) reset() error {
+	d.written = 0
+	d.hash.Reset()
+
+	if err := d.file.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := d.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// verify - check the size and checksums of the completed download
+func (d *download) verify(expected string) error {
+	if d.total >= 0 && d.written != d.total {
+		return fmt.Errorf("%w: expected %d bytes, received %d", ErrSizeMismatch, d.total, d.written)
+	}
+
+	sum := d.hash.Sum(nil)
+	if expected != "" && expected != hex.EncodeToString(sum) {
+		return fmt.Errorf("%w: expected sha256 %s, received %s", ErrChecksumMismatch, expected, hex.EncodeToString(sum))
+	}
+
+	if d.digest != nil && !bytes.Equal(d.digest, sum) {
+		return fmt.Errorf("%w: digest header does not match the received body", ErrChecksumMismatch)
+	}
+
+	return nil
+}
+
+// validatorOf - strong ETag, or Last-Modified, usable with If-Range
+func validatorOf(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return header.Get("Last-Modified")
+}
+
+// digestOf - SHA-256 from a RFC 3230 Digest header (SHA-256=<base64>) or a RFC 9530 Content-Digest header (sha-256=:<base64>:)
+func digestOf(digest, contentDigest string) []byte {
+	for _, value := range strings.Split(contentDigest, ",") {
+		algorithm, encoded, ok := strings.Cut(strings.TrimSpace(value), "=")
+		if ok && strings.EqualFold(algorithm, "sha-256") {
+			if sum, err := base64.StdEncoding.DecodeString(strings.Trim(encoded, ":")); err == nil {
+				return sum
+			}
+		}
+	}
+
+	for _, value := range strings.Split(digest, ",") {
+		algorithm, encoded, ok := strings.Cut(strings.TrimSpace(value), "=")
+		if ok && strings.EqualFold(algorithm, "sha-256") {
+			if sum, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				return sum
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseContentRange - start and complete length of a "bytes start-end/total" Content-Range, total is -1 when unknown
+func parseContentRange(value string) (int64, int64, bool) {
+	unit, spec, ok := strings.Cut(value, " ")
+	if !ok || unit != "bytes" {
+		return 0, 0, false
+	}
+
+	span, size, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, false
+	}
+
+	first, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	if size == "*" {
+		return start, -1, true
+	}
+
+	total, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return start, total, true
+}
@@ -0,0 +1,213 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// abortAfter - write the first n bytes of payload with the full Content-Length, then drop the connection
+func abortAfter(w http.ResponseWriter, payload []byte, n int) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(payload[:n])
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+func TestClient_Download(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+	var client *Client
+	var dst string
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+		dst = filepath.Join(t.TempDir(), "artifact.bin")
+	})
+
+	// leftoverFiles - files left in the destination directory besides dst
+	leftoverFiles := func(t *testing.T) int {
+		entries, err := os.ReadDir(filepath.Dir(dst))
+		odize.AssertNoError(t, err)
+
+		count := 0
+		for _, entry := range entries {
+			if entry.Name() != filepath.Base(dst) {
+				count++
+			}
+		}
+		return count
+	}
+
+	err := group.
+		Test("should download and verify the checksum", func(t *testing.T) {
+			sum := sha256.Sum256(payload)
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				odize.AssertEqual(t, "identity", r.Header.Get("Accept-Encoding"))
+				w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(payload))
+			})
+
+			result, err := client.Download(context.Background(), srv.URL, dst, WithSHA256(sha256Hex(payload)))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, int64(len(payload)), result.Size)
+			odize.AssertEqual(t, sha256Hex(payload), result.SHA256)
+			odize.AssertEqual(t, 0, result.Resumes)
+
+			data, err := os.ReadFile(dst)
+			odize.AssertNoError(t, err)
+			odize.AssertTrue(t, bytes.Equal(payload, data))
+			odize.AssertEqual(t, 0, leftoverFiles(t))
+		}).
+		Test("dropped connection should resume with range and if-range", func(t *testing.T) {
+			var ranges, ifRanges []string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+				w.Header().Set("ETag", `"v1"`)
+				if count == 1 {
+					abortAfter(w, payload, 1000)
+				}
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(payload))
+			})
+
+			result, err := client.Download(context.Background(), srv.URL, dst)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 1, result.Resumes)
+			odize.AssertEqual(t, []string{"", "bytes=1000-"}, ranges)
+			odize.AssertEqual(t, []string{"", `"v1"`}, ifRanges)
+
+			data, err := os.ReadFile(dst)
+			odize.AssertNoError(t, err)
+			odize.AssertTrue(t, bytes.Equal(payload, data))
+		}).
+		Test("changed resource should restart the download", func(t *testing.T) {
+			updated := bytes.Repeat([]byte("z"), 5000)
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				if count == 1 {
+					w.Header().Set("ETag", `"v1"`)
+					abortAfter(w, payload, 1000)
+				}
+				w.Header().Set("ETag", `"v2"`)
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(updated))
+			})
+
+			result, err := client.Download(context.Background(), srv.URL, dst)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, int64(len(updated)), result.Size)
+
+			data, err := os.ReadFile(dst)
+			odize.AssertNoError(t, err)
+			odize.AssertTrue(t, bytes.Equal(updated, data))
+		}).
+		Test("checksum mismatch should fail without creating the file", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(payload))
+			})
+
+			_, err := client.Download(context.Background(), srv.URL, dst, WithSHA256(sha256Hex([]byte("other"))))
+			odize.AssertTrue(t, errors.Is(err, ErrChecksumMismatch))
+
+			_, statErr := os.Stat(dst)
+			odize.AssertTrue(t, errors.Is(statErr, os.ErrNotExist))
+			odize.AssertEqual(t, 0, leftoverFiles(t))
+		}).
+		Test("digest header mismatch should fail", func(t *testing.T) {
+			sum := sha256.Sum256([]byte("other"))
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				w.Header().Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(payload))
+			})
+
+			_, err := client.Download(context.Background(), srv.URL, dst)
+			odize.AssertTrue(t, errors.Is(err, ErrChecksumMismatch))
+		}).
+		Test("client error should fail without resuming", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+
+			_, err := client.Download(context.Background(), srv.URL, dst)
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, int32(1), count.Load())
+			odize.AssertEqual(t, 0, leftoverFiles(t))
+		}).
+		Test("default client timeout should not cut off a slow download", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+				flusher, ok := w.(http.Flusher)
+				odize.AssertTrue(t, ok)
+				for chunk := range slices.Chunk(payload, len(payload)/4) {
+					_, _ = w.Write(chunk)
+					flusher.Flush()
+					time.Sleep(50 * time.Millisecond)
+				}
+			})
+
+			client = New(&Options{})
+			httpClient, ok := client.Client.(*http.Client)
+			odize.AssertTrue(t, ok)
+			httpClient.Timeout = 100 * time.Millisecond
+
+			result, err := client.Download(context.Background(), srv.URL, dst)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, int64(len(payload)), result.Size)
+			odize.AssertEqual(t, int32(1), count.Load())
+		}).
+		Test("server errors should make one attempt per resume", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+
+			client = New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond})))
+			_, err := client.Download(context.Background(), srv.URL, dst)
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, int32(4), count.Load())
+			odize.AssertEqual(t, 0, leftoverFiles(t))
+		}).
+		Test("without a retry strategy a dropped connection should fail", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				abortAfter(w, payload, 1000)
+			})
+
+			_, err := New(&Options{}).Download(context.Background(), srv.URL, dst)
+			odize.AssertError(t, err)
+			odize.AssertEqual(t, int32(1), count.Load())
+			odize.AssertEqual(t, 0, leftoverFiles(t))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, total, ok := parseContentRange("bytes 100-199/1000")
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, int64(100), start)
+	odize.AssertEqual(t, int64(1000), total)
+
+	start, total, ok = parseContentRange("bytes 5-9/*")
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, int64(5), start)
+	odize.AssertEqual(t, int64(-1), total)
+
+	_, _, ok = parseContentRange("items 0-1/2")
+	odize.AssertFalse(t, ok)
+}
@@ -7,6 +7,10 @@ import (
 
 var (
 	ErrNoValidRetryStrategy = errors.New("no valid retry strategy")
+	// ErrAttemptTimeout - a single attempt exceeded the attempt timeout, the attempt is retried
+	ErrAttemptTimeout = errors.New("attempt timeout exceeded")
+	// ErrTimeout - the overall deadline, including retries and backoff waits, was exceeded
+	ErrTimeout = errors.New("overall timeout exceeded")
 )
 
 type APIError struct {
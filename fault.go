@@ -0,0 +1,287 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrInjectedFault - the error was injected by a FaultInjector
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultKind - failure injected by a fault rule
+type FaultKind int
+
+const (
+	// FaultNone - only apply the rule latency
+	FaultNone FaultKind = iota
+	// FaultConnectionReset - fail the request with a connection reset, the request is not sent
+	FaultConnectionReset
+	// FaultTimeout - hang until the request context is done, or fail with a network timeout after the rule Timeout. The request is not sent
+	FaultTimeout
+	// FaultStatus - reply with the rule Status and an empty body, the request is not sent
+	FaultStatus
+	// FaultTruncate - cut the response body off after TruncateAfter bytes with io.ErrUnexpectedEOF
+	FaultTruncate
+	// FaultTrickle - deliver the response body TrickleBytes at a time, waiting TrickleInterval before each chunk
+	FaultTrickle
+)
+
+// FaultRule - inject a fault into matching requests
+type FaultRule struct {
+	// Method to match, empty matches every method
+	Method string
+	// Host to match, with or without the port, empty matches every host
+	Host string
+	// Path to match, may contain path.Match patterns, e.g. /users/*. Empty matches every path
+	Path string
+	// Probability of injecting the fault into a matching request, 0 never and 1 always
+	Probability float64
+	// Delay before the request is sent, applied before the fault
+	Latency time.Duration
+	Fault   FaultKind
+	// Status code replied by FaultStatus, default is 500
+	Status int
+	// Network timeout of FaultTimeout, 0 hangs until the request context is done
+	Timeout time.Duration
+	// Bytes of the response body delivered by FaultTruncate
+	TruncateAfter int64
+	// Chunk size and interval of FaultTrickle, default is 1 byte every 100ms
+	TrickleBytes    int
+	TrickleInterval time.Duration
+}
+
+// FaultInjector - injects faults into requests matching its rules, safe for concurrent use.
+// The first matching rule whose probability roll succeeds is applied, rolls come from a seeded generator so runs are reproducible.
+type FaultInjector struct {
+	rules []FaultRule
+	mu    sync.Mutex
+	rng   *rand.Rand
+}
+
+// NewFaultInjector - injector applying the rules in order, seeded for reproducible runs
+func NewFaultInjector(seed uint64, rules ...FaultRule) *FaultInjector {
+	return &FaultInjector{
+		rules: rules,
+		rng:   rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// WithFaultInjection - inject faults into every request by wrapping the HTTP client's transport.
+//
+// Example:
+//
+//	injector := fetch.NewFaultInjector(42,
+//		fetch.FaultRule{Path: "/orders/*", Probability: 0.2, Fault: fetch.FaultStatus, Status: http.StatusServiceUnavailable},
+//		fetch.FaultRule{Probability: 0.5, Latency: 300 * time.Millisecond},
+//	)
+//	client := fetch.New(fetch.WithOpts(fetch.WithFaultInjection(injector)))
+func WithFaultInjection(injector *FaultInjector) FnOpts {
+	return func(o *Options) error {
+		o.FaultInjector = injector
+		return nil
+	}
+}
+
+// FaultTransport - http.RoundTripper injecting faults before delegating to the wrapped transport
+type FaultTransport struct {
+	Injector *FaultInjector
+	// Transport sending requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+}
+
+var _ http.RoundTripper = (*FaultTransport)(nil)
+
+// Wrap - transport injecting faults into requests sent with base, http.DefaultTransport when nil
+func (f *FaultInjector) Wrap(base http.RoundTripper) *FaultTransport {
+	return &FaultTransport{Injector: f, Transport: base}
+}
+
+// RoundTrip - apply the first matching rule, then send the request unless the fault replaces it
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	rule, ok := t.Injector.pick(req)
+	if !ok {
+		return base.RoundTrip(req)
+	}
+
+	if rule.Latency > 0 {
+		if err := wait(req.Context(), rule.Latency); err != nil {
+			closeBody(req.Body)
+			return nil, err
+		}
+	}
+
+	switch rule.Fault {
+	case FaultConnectionReset:
+		closeBody(req.Body)
+		return nil, &injectedFaultError{&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+	case FaultTimeout:
+		closeBody(req.Body)
+		return nil, injectTimeout(req.Context(), rule.Timeout)
+	case FaultStatus:
+		closeBody(req.Body)
+		return injectedResponse(req, rule.Status), nil
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		return resp, err
+	}
+
+	switch rule.Fault {
+	case FaultTruncate:
+		resp.Body = &truncatedBody{ReadCloser: resp.Body, remaining: rule.TruncateAfter}
+	case FaultTrickle:
+		resp.Body = newTrickleBody(req.Context(), resp.Body, rule.TrickleBytes, rule.TrickleInterval)
+	}
+
+	return resp, nil
+}
+
+// pick - first matching rule whose probability roll succeeds
+func (f *FaultInjector) pick(req *http.Request) (FaultRule, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, rule := range f.rules {
+		if rule.matches(req) && f.rng.Float64() < rule.Probability {
+			return rule, true
+		}
+	}
+
+	return FaultRule{}, false
+}
+
+// matches - the request matches the rule method, host and path
+func (r FaultRule) matches(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+
+	if r.Host != "" && !strings.EqualFold(r.Host, req.URL.Host) && !strings.EqualFold(r.Host, req.URL.Hostname()) {
+		return false
+	}
+
+	if r.Path != "" {
+		if ok, err := path.Match(r.Path, req.URL.Path); err != nil || !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// injectTimeout - wait for the timeout or the request context, then fail like a network timeout
+func injectTimeout(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if err := wait(ctx, timeout); err != nil {
+		return err
+	}
+
+	return &injectedFaultError{&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}}
+}
+
+// injectedFaultError - network error matching ErrInjectedFault, Timeout and Temporary are kept so callers classify it like a real failure
+type injectedFaultError struct {
+	*net.OpError
+}
+
+func (e *injectedFaultError) Error() string {
+	return ErrInjectedFault.Error() + ": " + e.OpError.Error()
+}
+
+func (e *injectedFaultError) Unwrap() []error {
+	return []error{ErrInjectedFault, e.OpError}
+}
+
+// injectedResponse - empty response with the status
+func injectedResponse(req *http.Request, status int) *http.Response {
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Length": []string{"0"}},
+		Body:          http.NoBody,
+		ContentLength: 0,
+		Request:       req,
+	}
+}
+
+// truncatedBody - fails with io.ErrUnexpectedEOF once remaining bytes were read
+type truncatedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (t *truncatedBody) Read(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		return 0, fmt.Errorf("%w: %w", ErrInjectedFault, io.ErrUnexpectedEOF)
+	}
+
+	if int64(len(p)) > t.remaining {
+		p = p[:t.remaining]
+	}
+
+	n, err := t.ReadCloser.Read(p)
+	t.remaining -= int64(n)
+
+	return n, err
+}
+
+// trickleBody - delivers the body a chunk at a time
+type trickleBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	chunk    int
+	interval time.Duration
+}
+
+func newTrickleBody(ctx context.Context, body io.ReadCloser, chunk int, interval time.Duration) *trickleBody {
+	if chunk <= 0 {
+		chunk = 1
+	}
+
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	return &trickleBody{ReadCloser: body, ctx: ctx, chunk: chunk, interval: interval}
+}
+
+func (t *trickleBody) Read(p []byte) (int, error) {
+	if err := wait(t.ctx, t.interval); err != nil {
+		return 0, err
+	}
+
+	if len(p) > t.chunk {
+		p = p[:t.chunk]
+	}
+
+	return t.ReadCloser.Read(p)
+}
@@ -0,0 +1,165 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestClient_fault_injection(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var server func(t *testing.T) string
+
+	group.BeforeEach(func() {
+		server = func(t *testing.T) string {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte("hello world"))
+			})
+			return srv.URL
+		}
+	})
+
+	faultyClient := func(rules ...FaultRule) *Client {
+		return New(WithOpts(WithFaultInjection(NewFaultInjector(1, rules...))))
+	}
+
+	err := group.
+		Test("status faults should only apply to matching requests", func(t *testing.T) {
+			base := server(t)
+			client := faultyClient(FaultRule{Method: http.MethodPost, Path: "/orders/*", Probability: 1, Fault: FaultStatus, Status: http.StatusTeapot})
+
+			resp, err := client.Post(base+"/orders/1", nil, nil)
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, http.StatusTeapot, apiErr.StatusCode)
+			readBody(t, resp)
+
+			resp, err = client.Get(base+"/orders/1", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello world", readBody(t, resp))
+
+			resp, err = client.Post(base+"/users/1", nil, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+		}).
+		Test("host should match with or without the port", func(t *testing.T) {
+			base := server(t)
+			parsed, err := url.Parse(base)
+			odize.AssertNoError(t, err)
+
+			for _, host := range []string{parsed.Host, parsed.Hostname()} {
+				client := faultyClient(FaultRule{Host: host, Probability: 1, Fault: FaultStatus})
+				resp, err := client.Get(base, nil)
+				odize.AssertEqual(t, http.StatusInternalServerError, resp.StatusCode)
+				odize.AssertError(t, err)
+			}
+
+			client := faultyClient(FaultRule{Host: "example.com", Probability: 1, Fault: FaultStatus})
+			resp, err := client.Get(base, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+		}).
+		Test("connection resets should be injected", func(t *testing.T) {
+			client := faultyClient(FaultRule{Probability: 1, Fault: FaultConnectionReset})
+
+			_, err := client.Get(server(t), nil)
+			odize.AssertTrue(t, errors.Is(err, ErrInjectedFault))
+			odize.AssertTrue(t, errors.Is(err, syscall.ECONNRESET))
+		}).
+		Test("timeouts should hang until the attempt timeout", func(t *testing.T) {
+			client := New(WithOpts(
+				WithFaultInjection(NewFaultInjector(1, FaultRule{Probability: 1, Fault: FaultTimeout})),
+				WithAttemptTimeout(20*time.Millisecond),
+			))
+
+			_, err := client.Get(server(t), nil)
+			odize.AssertTrue(t, errors.Is(err, ErrAttemptTimeout))
+		}).
+		Test("timeouts should fail as network timeouts after the rule timeout", func(t *testing.T) {
+			client := faultyClient(FaultRule{Probability: 1, Fault: FaultTimeout, Timeout: 10 * time.Millisecond})
+
+			_, err := client.Get(server(t), nil)
+			var netErr net.Error
+			odize.AssertTrue(t, errors.As(err, &netErr))
+			odize.AssertTrue(t, netErr.Timeout())
+		}).
+		Test("latency should delay the request", func(t *testing.T) {
+			client := faultyClient(FaultRule{Probability: 1, Latency: 50 * time.Millisecond})
+
+			start := time.Now()
+			resp, err := client.Get(server(t), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello world", readBody(t, resp))
+			odize.AssertTrue(t, time.Since(start) >= 50*time.Millisecond)
+		}).
+		Test("truncated bodies should fail with an unexpected EOF", func(t *testing.T) {
+			client := faultyClient(FaultRule{Probability: 1, Fault: FaultTruncate, TruncateAfter: 5})
+
+			resp, err := client.Get(server(t), nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			odize.AssertTrue(t, errors.Is(err, io.ErrUnexpectedEOF))
+			odize.AssertEqual(t, "hello", string(body))
+		}).
+		Test("trickled bodies should be delivered in chunks", func(t *testing.T) {
+			client := faultyClient(FaultRule{Probability: 1, Fault: FaultTrickle, TrickleBytes: 4, TrickleInterval: 10 * time.Millisecond})
+
+			start := time.Now()
+			resp, err := client.Get(server(t), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello world", readBody(t, resp))
+			odize.AssertTrue(t, time.Since(start) >= 30*time.Millisecond)
+		}).
+		Test("the same seed should inject the same faults", func(t *testing.T) {
+			rolls := func(seed uint64) []bool {
+				injector := NewFaultInjector(seed, FaultRule{Probability: 0.5, Fault: FaultStatus})
+				req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+				odize.AssertNoError(t, err)
+
+				var injected []bool
+				for range 64 {
+					_, ok := injector.pick(req)
+					injected = append(injected, ok)
+				}
+				return injected
+			}
+
+			first := rolls(7)
+			odize.AssertEqual(t, first, rolls(7))
+			odize.AssertTrue(t, contains(first, true) && contains(first, false))
+		}).
+		Test("custom HTTP clients should be wrapped without modifying them", func(t *testing.T) {
+			httpClient := &http.Client{}
+			client := New(WithOpts(
+				WithHTTPClient(httpClient),
+				WithFaultInjection(NewFaultInjector(1, FaultRule{Probability: 1, Fault: FaultStatus})),
+			))
+
+			resp, err := client.Get(server(t), nil)
+			odize.AssertError(t, err)
+			odize.AssertEqual(t, http.StatusInternalServerError, resp.StatusCode)
+			odize.AssertNil(t, httpClient.Transport)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func contains(values []bool, value bool) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,6 +5,7 @@ package fetch
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -21,14 +22,51 @@ func New(options *Options) *Client {
 	// defaults
 	var fetch Client
 	fetch.DefaultHeaders = options.DefaultHeaders
-	fetch.Client = setDefaultClient()
+	fetch.Signer = options.Signer
+	transport := setDefaultTransport()
+	if options.TLS != nil {
+		transport.TLSClientConfig = options.TLS.config()
+	}
+	if options.Transport != nil {
+		options.Transport.apply(transport)
+	}
+	transport.DisableCompression = options.DisableDecompression
+	httpClient := setDefaultClient(transport)
+	if options.AttemptTimeout > 0 {
+		httpClient.Timeout = 0
+	}
+	fetch.Client = httpClient
+	fetch.AttemptTimeout = options.AttemptTimeout
+	fetch.Timeout = options.Timeout
+	fetch.Cache = options.Cache
+	fetch.ConditionalStore = options.ConditionalStore
+	fetch.Coalescer = options.Coalescer
+	fetch.DisableDecompression = options.DisableDecompression
+	fetch.MaxDecompressedSize = options.MaxDecompressedSize
+	fetch.RequestCompression = options.RequestCompression
+	fetch.Progress = options.Progress
+	fetch.ProgressInterval = options.ProgressInterval
+	fetch.MaxResponseSize = options.MaxResponseSize
+	fetch.HAR = options.HAR
+	fetch.CurlLog = options.CurlLog
+	fetch.CurlLogRedact = options.CurlLogRedact
 	if options.WithRetry {
 		fetch.RetryStrategy = setDefaultRetryStrategy()
 	}
 
+	if options.FaultInjector != nil {
+		httpClient.Transport = options.FaultInjector.Wrap(transport)
+	}
+
 	// overrides
 	if options.HTTPClient != nil {
 		fetch.Client = options.HTTPClient
+		if options.FaultInjector != nil {
+			// wrap a copy, the caller's client is left untouched
+			faulty := *options.HTTPClient
+			faulty.Transport = options.FaultInjector.Wrap(options.HTTPClient.Transport)
+			fetch.Client = &faulty
+		}
 	}
 
 	if options.RetryStrategy != nil {
@@ -53,7 +91,7 @@ func New(options *Options) *Client {
 //	}
 func (a *Client) Get(url string, headers map[string]string) (*http.Response, error) {
 	ctx := context.Background()
-	return a.do(ctx, url, http.MethodGet, nil, headers)
+	return a.do(ctx, &Request{Method: http.MethodGet, URL: url, Headers: headers})
 }
 
 // Post sends an HTTP POST request to the specified URL with a body and optional headers, returning the HTTP response or an error.
@@ -71,7 +109,7 @@ func (a *Client) Get(url string, headers map[string]string) (*http.Response, err
 //	}
 func (a *Client) Post(url string, body io.Reader, headers map[string]string) (*http.Response, error) {
 	ctx := context.Background()
-	return a.do(ctx, url, http.MethodPost, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPost, URL: url, Body: body, Headers: headers})
 }
 
 // Put sends an HTTP PUT request to the specified URL with a body and optional headers, returning the HTTP response or an error.
@@ -89,7 +127,7 @@ func (a *Client) Post(url string, body io.Reader, headers map[string]string) (*h
 //	}
 func (a *Client) Put(url string, body io.Reader, headers map[string]string) (*http.Response, error) {
 	ctx := context.Background()
-	return a.do(ctx, url, http.MethodPut, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPut, URL: url, Body: body, Headers: headers})
 }
 
 // Delete sends an HTTP DELETE request to the specified URL with a body and optional headers, returning the response or an error.
@@ -107,7 +145,7 @@ func (a *Client) Put(url string, body io.Reader, headers map[string]string) (*ht
 //	}
 func (a *Client) Delete(url string, body io.Reader, headers map[string]string) (*http.Response, error) {
 	ctx := context.Background()
-	return a.do(ctx, url, http.MethodDelete, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodDelete, URL: url, Body: body, Headers: headers})
 }
 
 // Patch sends an HTTP PATCH request to the specified URL with a body and optional headers, returning the response or an error.
@@ -124,7 +162,7 @@ func (a *Client) Delete(url string, body io.Reader, headers map[string]string) (
 //	}
 func (a *Client) Patch(url string, body io.Reader, headers map[string]string) (*http.Response, error) {
 	ctx := context.Background()
-	return a.do(ctx, url, http.MethodPatch, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPatch, URL: url, Body: body, Headers: headers})
 }
 
 // GetCtx sends a cancelable HTTP GET request to the specified URL with context and optional headers, returning the response or an error.
@@ -145,7 +183,7 @@ func (a *Client) Patch(url string, body io.Reader, headers map[string]string) (*
 //		// Handle non-API Error
 //	}
 func (a *Client) GetCtx(ctx context.Context, url string, headers map[string]string) (*http.Response, error) {
-	return a.do(ctx, url, http.MethodGet, nil, headers)
+	return a.do(ctx, &Request{Method: http.MethodGet, URL: url, Headers: headers})
 }
 
 // PostCtx sends a cancelable HTTP POST request to the specified URL with context, body, and headers, returning a response or error.
@@ -166,7 +204,7 @@ func (a *Client) GetCtx(ctx context.Context, url string, headers map[string]stri
 //		// Handle non-API Error
 //	}
 func (a *Client) PostCtx(ctx context.Context, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	return a.do(ctx, url, http.MethodPost, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPost, URL: url, Body: body, Headers: headers})
 }
 
 // PutCtx sends a cancelable HTTP PUT request to the specified URL with context, body, and headers, returning a response or error.
@@ -187,7 +225,7 @@ func (a *Client) PostCtx(ctx context.Context, url string, body io.Reader, header
 //		// Handle non-API Error
 //	}
 func (a *Client) PutCtx(ctx context.Context, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	return a.do(ctx, url, http.MethodPut, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPut, URL: url, Body: body, Headers: headers})
 }
 
 // DeleteCtx sends a cancelable HTTP DELETE request to the specified URL with context, body, and headers, returning a response or error.
@@ -208,7 +246,7 @@ func (a *Client) PutCtx(ctx context.Context, url string, body io.Reader, headers
 //		// Handle non-API Error
 //	}
 func (a *Client) DeleteCtx(ctx context.Context, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	return a.do(ctx, url, http.MethodDelete, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodDelete, URL: url, Body: body, Headers: headers})
 }
 
 // PatchCtx sends an HTTP PATCH request to the specified URL with the provided context, body, and headers.
@@ -229,20 +267,93 @@ func (a *Client) DeleteCtx(ctx context.Context, url string, body io.Reader, head
 //		// Handle non-API Error
 //	}
 func (a *Client) PatchCtx(ctx context.Context, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
-	return a.do(ctx, url, http.MethodPatch, body, headers)
+	return a.do(ctx, &Request{Method: http.MethodPatch, URL: url, Body: body, Headers: headers})
+}
+
+// Do sends the request with context, applying any per request configuration such as timeouts.
+//
+// Example:
+//
+//	var apiErr *fetch.APIError
+//
+//	resp, err := client.Do(ctx, &fetch.Request{
+//		Method:         http.MethodGet,
+//		URL:            url,
+//		AttemptTimeout: 2 * time.Second,
+//		Timeout:        10 * time.Second,
+//	})
+//	if err != nil {
+//		if errors.Is(err, fetch.ErrTimeout) {
+//			// Handle overall deadline exceeded
+//		}
+//		if errors.As(err, &apiErr) {
+//			fmt.Println("API Response error", apiErr)
+//		}
+//		// Handle non-API Error
+//	}
+func (a *Client) Do(ctx context.Context, r *Request) (*http.Response, error) {
+	return a.do(ctx, r)
 }
 
 // do - make http call with the provided configuration
-func (a *Client) do(ctx context.Context, url string, method string, body io.Reader, headers map[string]string) (*http.Response, error) {
+func (a *Client) do(ctx context.Context, r *Request) (*http.Response, error) {
+	if a.Coalescer != nil {
+		return a.doCoalesced(ctx, r, a.dispatch)
+	}
+
+	return a.dispatch(ctx, r)
+}
+
+// dispatch - send the request through the cache or conditional store when configured
+func (a *Client) dispatch(ctx context.Context, r *Request) (*http.Response, error) {
+	if a.Cache != nil {
+		return a.doCached(ctx, r)
+	}
+
+	if a.ConditionalStore != nil {
+		return a.doConditional(ctx, r)
+	}
+
+	return a.send(ctx, r)
+}
+
+// send - make http call, compressing the request body when configured
+func (a *Client) send(ctx context.Context, r *Request) (*http.Response, error) {
+	if a.RequestCompression != nil && r.Body != nil {
+		return a.sendCompressed(ctx, r)
+	}
+
+	return a.transmit(ctx, r)
+}
+
+// transmit - make http call with the timeouts and retry strategy applied
+func (a *Client) transmit(ctx context.Context, r *Request) (*http.Response, error) {
+	ctx, cancel := a.withTimeout(ctx, r)
+	resp, err := a.exchange(ctx, r)
+
+	return releaseOnClose(resp, cancel), err
+}
+
+// sendOnce - make a single http call within the overall timeout, for callers that own the retries such as reconnects and resumes
+func (a *Client) sendOnce(ctx context.Context, r *Request) (*http.Response, error) {
+	ctx, cancel := a.withTimeout(ctx, r)
+	resp, err := a.call(a.withHARCall(ctx), r)
+
+	return releaseOnClose(resp, cancel), err
+}
+
+// exchange - make http call with the retry strategy applied, within the overall timeout already set on ctx
+func (a *Client) exchange(ctx context.Context, r *Request) (*http.Response, error) {
+	ctx = a.withHARCall(ctx)
 	if a.RetryStrategy == nil {
-		return a.call(ctx, url, method, body, headers, a.DefaultHeaders)
+		return a.call(ctx, r)
 	}
 
-	return a.callWithRetry(ctx, url, method, body, headers, a.DefaultHeaders)
+	return a.callWithRetry(ctx, r)
 }
 
 // callWithRetry - wrap the call method with the retry strategy
-func (a *Client) callWithRetry(ctx context.Context, url string, method string, body io.Reader, headers ...map[string]string) (*http.Response, error) {
+func (a *Client) callWithRetry(ctx context.Context, r *Request) (*http.Response, error) {
 	logPrefix := "fetch: callWithRetry"
 	var resp *http.Response
 	var err error
@@ -251,42 +362,125 @@ func (a *Client) callWithRetry(ctx context.Context, url string, method string, b
 		return resp, ErrNoValidRetryStrategy
 	}
 
-	for _, retryWait := range a.RetryStrategy {
-		resp, err = a.call(ctx, url, method, body, headers...)
+	r, release, err := replayable(r)
+	if err != nil {
+		return resp, err
+	}
+	defer release()
+
+	rewind, err := rewindFunc(r.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	for i, retryWait := range a.RetryStrategy {
+		if i > 0 {
+			discard(resp)
+			if err = rewind(); err != nil {
+				return resp, err
+			}
+		}
+
+		resp, err = a.call(withAttempt(ctx, i+1), r)
 
 		if err == nil || !isRecoverable(err) {
 			if errors.Is(err, context.Canceled) {
-				log.Printf("%s: http %s request canceled", logPrefix, method)
+				log.Printf("%s: http %s request canceled", logPrefix, r.Method)
 			}
 
 			break
 		}
 
-		log.Printf("%s: http %s request error [%s], will retry in [%s]", logPrefix, method, err, retryWait)
-		time.Sleep(retryWait)
+		if i == len(a.RetryStrategy)-1 {
+			break
+		}
+
+		log.Printf("%s: http %s request error [%s], will retry in [%s]", logPrefix, r.Method, err, retryWait)
+		if waitErr := wait(ctx, retryWait); waitErr != nil {
+			return resp, classifyTimeout(ctx, ctx, waitErr)
+		}
 	}
 
 	return resp, err
 }
 
 // call - creates a new HTTP request and returns an HTTP response
-func (a *Client) call(ctx context.Context, url string, method string, body io.Reader, headers ...map[string]string) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+func (a *Client) call(ctx context.Context, r *Request) (*http.Response, error) {
+	attemptCtx, cancel := a.withAttemptTimeout(ctx, r)
+	httpClient, headersReceived := a.Client, func() {}
+	if r.stream {
+		httpClient, attemptCtx, cancel, headersReceived = a.streamClient(attemptCtx, cancel)
+	}
+
+	var body io.Reader = r.Body
+	if r.GetBody != nil {
+		var err error
+		if body, err = r.GetBody(); err != nil {
+			cancel()
+			return &http.Response{}, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, r.Method, r.URL, body)
 	if err != nil {
+		closeBody(body)
+		cancel()
 		return &http.Response{}, err
 	}
 
-	allHeaders := mergeHeaders(headers...)
+	if r.GetBody != nil {
+		req.GetBody = r.GetBody
+		if r.ContentLength > 0 {
+			req.ContentLength = r.ContentLength
+		}
+	}
+
+	allHeaders := mergeHeaders(r.Headers, a.DefaultHeaders)
 	for key, value := range allHeaders {
 		req.Header.Add(key, value)
 	}
 
+	a.negotiateEncoding(req)
+
 	log.Println("request", req == nil)
 
-	resp, err := a.Client.Do(req)
+	if a.Signer != nil {
+		if err = a.Signer.Sign(req); err != nil {
+			closeBody(body)
+			cancel()
+			return &http.Response{}, err
+		}
+	}
+
+	a.logCurl(req, r.GetBody != nil)
+
+	// after signing and logging, which may read the body again through GetBody
+	progress := a.progressOf(r, attemptOf(ctx))
+	progress.upload(req)
+
+	req, har := a.HAR.begin(ctx, req)
+
+	resp, err := httpClient.Do(req)
+	headersReceived()
 	if err != nil {
+		har.fail(err)
+		err = classifyTimeout(ctx, attemptCtx, err)
+		cancel()
 		return resp, err
 	}
+	har.respond(resp)
+
+	a.decompress(resp)
+	if err = a.rejectTooLarge(r, resp); err != nil {
+		har.fail(err)
+		discard(resp)
+		cancel()
+		return resp, err
+	}
+	a.limitBody(r, resp)
+	har.capture(resp)
+	progress.download(resp)
+	resp = releaseOnClose(resp, cancel)
 
 	if resp.StatusCode > 399 {
 		return resp, &APIError{
@@ -298,6 +492,196 @@ func (a *Client) call(ctx context.Context, url string, method string, body io.Re
 	return resp, err
 }
 
+// withTimeout - apply the overall deadline, including backoff waits, to the context
+func (a *Client) withTimeout(ctx context.Context, r *Request) (context.Context, context.CancelFunc) {
+	timeout := a.Timeout
+	if r.Timeout > 0 {
+		timeout = r.Timeout
+	}
+
+	if timeout <= 0 {
+		return ctx, nil
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+}
+
+// withAttemptTimeout - apply the per attempt timeout to the context
+func (a *Client) withAttemptTimeout(ctx context.Context, r *Request) (context.Context, context.CancelFunc) {
+	timeout := a.AttemptTimeout
+	if r.AttemptTimeout > 0 {
+		timeout = r.AttemptTimeout
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, ErrAttemptTimeout)
+}
+
+// streamClient - a copy of the http.Client without its Timeout, which would cut off long lived bodies.
+// The timeout bounds the wait for the response headers instead, call headersReceived once Do returns
+func (a *Client) streamClient(ctx context.Context, cancel context.CancelFunc) (httpClient, context.Context, context.CancelFunc, func()) {
+	client, ok := a.Client.(*http.Client)
+	if !ok || client.Timeout <= 0 {
+		return a.Client, ctx, cancel, func() {}
+	}
+
+	stream := *client
+	stream.Timeout = 0
+
+	headerCtx, cancelHeader := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(client.Timeout, func() { cancelHeader(ErrAttemptTimeout) })
+
+	release := func() {
+		cancelHeader(nil)
+		cancel()
+	}
+
+	return &stream, headerCtx, release, func() { timer.Stop() }
+}
+
+// classifyTimeout - distinguish the overall deadline from an attempt timeout
+func classifyTimeout(ctx context.Context, attemptCtx context.Context, err error) error {
+	switch {
+	case errors.Is(context.Cause(ctx), ErrTimeout):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(context.Cause(attemptCtx), ErrAttemptTimeout):
+		return fmt.Errorf("%w: %w", ErrAttemptTimeout, err)
+	}
+
+	return err
+}
+
+// wait - wait for the backoff duration or until the context is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// releaseOnClose - release the context once the response body is closed, the body can still be read after returning
+func releaseOnClose(resp *http.Response, cancel context.CancelFunc) *http.Response {
+	if cancel == nil {
+		return resp
+	}
+
+	if resp == nil || resp.Body == nil {
+		cancel()
+		return resp
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp
+}
+
+// attemptKey - context key holding the attempt number
+type attemptKey struct{}
+
+// withAttempt - record the attempt number, starting at 1, in the context
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptOf - attempt number of the call, 1 when the call is not retried
+func attemptOf(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+
+	return 1
+}
+
+// closeBody - close a request body that was not sent
+func closeBody(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// discard - drain and close the body of a response that will not be returned, so the connection can be reused
+func discard(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+}
+
+// cancelOnClose - cancels the request context when the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// rewindFunc - returns a func that resets a seekable body to its starting offset, so each retry sends the full body
+func rewindFunc(body io.Reader) (func() error, error) {
+	seeker, ok := body.(io.Seeker)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}, nil
+}
+
+// replayable - the request with a body that can be sent on every attempt. net/http closes the request body after an attempt,
+// so seekable bodies that are closers, e.g. *os.File, are read through GetBody or with Close hidden, and closed by release once the call finishes
+func replayable(r *Request) (*Request, func(), error) {
+	closer, ok := r.Body.(io.Closer)
+	seeker, isSeeker := r.Body.(io.ReadSeeker)
+	if r.GetBody != nil || !ok || !isSeeker {
+		return r, func() {}, nil
+	}
+
+	release := func() { _ = closer.Close() }
+	replay := *r
+
+	readerAt, ok := r.Body.(io.ReaderAt)
+	if !ok {
+		replay.Body = struct{ io.ReadSeeker }{seeker}
+		return &replay, release, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return r, func() {}, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return r, func() {}, err
+	}
+
+	replay.Body = nil
+	replay.ContentLength = end - start
+	replay.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(readerAt, start, end-start)), nil
+	}
+
+	return &replay, release, nil
+}
+
 // mergeHeaders - merge a slice of headers
 func mergeHeaders(headersList ...map[string]string) map[string]string {
 	mergedHeaders := map[string]string{}
@@ -311,8 +695,15 @@ func mergeHeaders(headersList ...map[string]string) map[string]string {
 	return mergedHeaders
 }
 
-// isRecoverable - checks if the response status code not within the 4XX range or is non standard status code
+// isRecoverable - checks if the attempt timed out or the response status code not within the 4XX range or is non standard status code
 func isRecoverable(err error) bool {
+	if errors.Is(err, ErrTimeout) {
+		return false
+	}
+	if errors.Is(err, ErrAttemptTimeout) {
+		return true
+	}
+
 	var apiError *APIError
 	if !errors.As(err, &apiError) {
 		return false
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -616,3 +620,212 @@ func TestClient_context_methods(t *testing.T) {
 		Run()
 	odize.AssertNoError(t, err)
 }
+
+// blockingHTTPClient - mock client that blocks each attempt for the delay or until the request context is done
+type blockingHTTPClient struct {
+	MockHTTPClient
+	delays []time.Duration
+}
+
+func (b *blockingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	delay := time.Duration(0)
+	if b.Retries < len(b.delays) {
+		delay = b.delays[b.Retries]
+	}
+	b.Retries++
+
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-time.After(delay):
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	}
+}
+
+func TestClient_timeouts(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("attempt timeout should retry and succeed", func(t *testing.T) {
+			m := &blockingHTTPClient{delays: []time.Duration{time.Second, 0}}
+			c := Client{
+				Client:         m,
+				RetryStrategy:  []time.Duration{time.Millisecond, time.Millisecond},
+				AttemptTimeout: 10 * time.Millisecond,
+			}
+
+			resp, err := c.Get("http://example.com", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 2, m.Retries)
+
+			body, err := io.ReadAll(resp.Body)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "ok", string(body))
+			odize.AssertNoError(t, resp.Body.Close())
+		}).
+		Test("attempt timeout should be distinguishable", func(t *testing.T) {
+			m := &blockingHTTPClient{delays: []time.Duration{time.Second}}
+			c := Client{
+				Client:         m,
+				AttemptTimeout: 10 * time.Millisecond,
+			}
+
+			_, err := c.Get("http://example.com", nil)
+			odize.AssertTrue(t, errors.Is(err, ErrAttemptTimeout))
+			odize.AssertTrue(t, errors.Is(err, context.DeadlineExceeded))
+			odize.AssertFalse(t, errors.Is(err, ErrTimeout))
+		}).
+		Test("overall timeout should include backoff waits", func(t *testing.T) {
+			m := &MockHTTPClient{
+				Resp: &http.Response{StatusCode: http.StatusServiceUnavailable},
+			}
+			c := Client{
+				Client:        m,
+				RetryStrategy: []time.Duration{time.Second, time.Second},
+				Timeout:       20 * time.Millisecond,
+			}
+
+			start := time.Now()
+			_, err := c.Get("http://example.com", nil)
+			odize.AssertTrue(t, errors.Is(err, ErrTimeout))
+			odize.AssertFalse(t, errors.Is(err, ErrAttemptTimeout))
+			odize.AssertTrue(t, time.Since(start) < time.Second)
+			odize.AssertEqual(t, 1, m.Retries)
+		}).
+		Test("overall timeout should not be retried", func(t *testing.T) {
+			m := &blockingHTTPClient{delays: []time.Duration{time.Second, time.Second}}
+			c := Client{
+				Client:         m,
+				RetryStrategy:  []time.Duration{time.Millisecond, time.Millisecond},
+				AttemptTimeout: time.Second,
+				Timeout:        10 * time.Millisecond,
+			}
+
+			_, err := c.Get("http://example.com", nil)
+			odize.AssertTrue(t, errors.Is(err, ErrTimeout))
+			odize.AssertEqual(t, 1, m.Retries)
+		}).
+		Test("per request timeouts should override the client", func(t *testing.T) {
+			m := &blockingHTTPClient{delays: []time.Duration{50 * time.Millisecond, 50 * time.Millisecond}}
+			c := Client{
+				Client:         m,
+				AttemptTimeout: 10 * time.Millisecond,
+			}
+
+			resp, err := c.Do(context.Background(), &Request{
+				Method:         http.MethodGet,
+				URL:            "http://example.com",
+				AttemptTimeout: time.Second,
+			})
+			odize.AssertNoError(t, err)
+			odize.AssertNoError(t, resp.Body.Close())
+
+			_, err = c.Do(context.Background(), &Request{
+				Method:  http.MethodGet,
+				URL:     "http://example.com",
+				Timeout: time.Millisecond,
+			})
+			odize.AssertTrue(t, errors.Is(err, ErrTimeout))
+		}).
+		Test("body should be readable until closed", func(t *testing.T) {
+			m := &blockingHTTPClient{}
+			c := Client{
+				Client:         m,
+				AttemptTimeout: time.Second,
+				Timeout:        time.Second,
+			}
+
+			resp, err := c.Get("http://example.com", nil)
+			odize.AssertNoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "ok", string(body))
+			odize.AssertNoError(t, resp.Body.Close())
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestClient_retry_should_resend_seekable_body(t *testing.T) {
+	var bodies []string
+	m := MockHTTPClient{
+		Resp: &http.Response{StatusCode: http.StatusServiceUnavailable},
+	}
+	c := &Client{
+		RetryStrategy: []time.Duration{time.Nanosecond, time.Nanosecond},
+		Client: &recordingHTTPClient{
+			MockHTTPClient: &m,
+			onDo: func(req *http.Request) {
+				data, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(data))
+			},
+		},
+	}
+
+	_, err := c.Post("http://example.com", strings.NewReader("payload"), nil)
+	odize.AssertError(t, err)
+	odize.AssertEqual(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestClient_retry_should_resend_file_body(t *testing.T) {
+	var bodies []string
+	srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	path := filepath.Join(t.TempDir(), "body.json")
+	odize.AssertNoError(t, os.WriteFile(path, []byte(`{"id":1}`), 0o600))
+	file, err := os.Open(path)
+	odize.AssertNoError(t, err)
+
+	c := New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+	resp, err := c.Post(srv.URL, file, nil)
+	odize.AssertNoError(t, err)
+	readBody(t, resp)
+	odize.AssertEqual(t, []string{`{"id":1}`, `{"id":1}`}, bodies)
+
+	_, err = file.Read(make([]byte, 1))
+	odize.AssertTrue(t, errors.Is(err, os.ErrClosed))
+}
+
+// closeRecorder - records whether the body was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestClient_sign_error_should_close_body(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	signErr := errors.New("sign failed")
+	c := &Client{
+		Client: &MockHTTPClient{Resp: &http.Response{StatusCode: http.StatusOK}},
+		Signer: signerFunc(func(_ *http.Request) error { return signErr }),
+	}
+
+	_, err := c.Do(context.Background(), &Request{
+		Method:  http.MethodPost,
+		URL:     "http://example.com",
+		GetBody: func() (io.ReadCloser, error) { return body, nil },
+	})
+	odize.AssertTrue(t, errors.Is(err, signErr))
+	odize.AssertTrue(t, body.closed)
+}
+
+func TestNew_with_attempt_timeout_replaces_client_timeout(t *testing.T) {
+	c := New(WithOpts(WithAttemptTimeout(time.Second), WithTimeout(5*time.Second)))
+
+	httpClient, ok := c.Client.(*http.Client)
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, time.Duration(0), httpClient.Timeout)
+	odize.AssertEqual(t, time.Second, c.AttemptTimeout)
+	odize.AssertEqual(t, 5*time.Second, c.Timeout)
+}
@@ -0,0 +1,494 @@
+package fetchtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInteractionNotFound - replaying and no unused recorded interaction matches the request
+	ErrInteractionNotFound = errors.New("fetchtest: no recorded interaction matches the request")
+	// ErrInvalidMode - the recorder mode is unknown
+	ErrInvalidMode = errors.New("fetchtest: invalid recorder mode")
+)
+
+// Redacted - value replacing redacted headers
+const Redacted = "REDACTED"
+
+// bodyBase64 - body encoding of bodies that are not valid UTF-8
+const bodyBase64 = "base64"
+
+// Mode - how the recorder uses the cassette
+type Mode int
+
+const (
+	// ModeReplay - replay recorded interactions, requests without a recorded interaction fail. The cassette must exist
+	ModeReplay Mode = iota
+	// ModeRecord - send every request and record a new cassette, replacing the existing one
+	ModeRecord
+	// ModeRecordMissing - replay recorded interactions, send and record requests without one
+	ModeRecordMissing
+	// ModePassthrough - send every request, nothing is replayed or recorded
+	ModePassthrough
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeRecordMissing:
+		return "record-missing"
+	case ModePassthrough:
+		return "passthrough"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode - mode from its name, e.g. from an environment variable
+func ParseMode(name string) (Mode, error) {
+	for _, mode := range []Mode{ModeReplay, ModeRecord, ModeRecordMissing, ModePassthrough} {
+		if strings.EqualFold(name, mode.String()) {
+			return mode, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrInvalidMode, name)
+}
+
+// Cassette - recorded interactions, stored as YAML (.yaml / .yml) or JSON
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction - a recorded request and its response
+type Interaction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// CassetteRequest - a recorded request
+type CassetteRequest struct {
+	Method       string      `json:"method" yaml:"method"`
+	URL          string      `json:"url" yaml:"url"`
+	Headers      http.Header `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body         string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"`
+}
+
+// CassetteResponse - a recorded response
+type CassetteResponse struct {
+	Status       int         `json:"status" yaml:"status"`
+	Headers      http.Header `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body         string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty" yaml:"body_encoding,omitempty"`
+}
+
+// RequestBody - decoded request body
+func (c CassetteRequest) RequestBody() ([]byte, error) {
+	return decodeBody(c.Body, c.BodyEncoding)
+}
+
+// ResponseBody - decoded response body
+func (c CassetteResponse) ResponseBody() ([]byte, error) {
+	return decodeBody(c.Body, c.BodyEncoding)
+}
+
+// Matcher - reports whether a recorded interaction matches the request. The body is the request body after redaction
+type Matcher func(req *http.Request, body []byte, recorded *Interaction) bool
+
+// DefaultMatcher - matches the method and URL
+func DefaultMatcher(req *http.Request, _ []byte, recorded *Interaction) bool {
+	return req.Method == recorded.Request.Method && req.URL.String() == recorded.Request.URL
+}
+
+// MatchBody - matches the method, URL and body
+func MatchBody(req *http.Request, body []byte, recorded *Interaction) bool {
+	if !DefaultMatcher(req, body, recorded) {
+		return false
+	}
+
+	recordedBody, err := recorded.Request.RequestBody()
+	return err == nil && bytes.Equal(body, recordedBody)
+}
+
+// MatchHeaders - matches the method, URL and the values of the headers
+func MatchHeaders(names ...string) Matcher {
+	return func(req *http.Request, body []byte, recorded *Interaction) bool {
+		if !DefaultMatcher(req, body, recorded) {
+			return false
+		}
+
+		for _, name := range names {
+			if strings.Join(req.Header.Values(name), ",") != strings.Join(recorded.Request.Headers.Values(name), ",") {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// RecorderOptions - configuration for the recorder
+type RecorderOptions struct {
+	// Transport sending requests when recording or passing through, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	// Matcher selecting the recorded interaction for a request, DefaultMatcher when nil
+	Matcher Matcher
+	// Headers replaced with Redacted in the cassette
+	RedactHeaders []string
+	// Redact request and response bodies before they are stored
+	RedactBody func(body []byte) []byte
+}
+
+// RecorderOpts - configure the recorder
+type RecorderOpts func(o *RecorderOptions)
+
+// WithRecorderTransport - send requests with the transport when recording or passing through
+func WithRecorderTransport(transport http.RoundTripper) RecorderOpts {
+	return func(o *RecorderOptions) {
+		o.Transport = transport
+	}
+}
+
+// WithMatcher - select recorded interactions with the matcher
+func WithMatcher(matcher Matcher) RecorderOpts {
+	return func(o *RecorderOptions) {
+		o.Matcher = matcher
+	}
+}
+
+// WithRedactHeaders - replace the request and response headers with Redacted in the cassette
+func WithRedactHeaders(names ...string) RecorderOpts {
+	return func(o *RecorderOptions) {
+		o.RedactHeaders = append(o.RedactHeaders, names...)
+	}
+}
+
+// WithRedactBody - redact request and response bodies before they are stored. Request bodies are redacted before matching.
+// Accept-Encoding is removed from recorded requests, so response bodies are redacted and stored uncompressed
+func WithRedactBody(redact func(body []byte) []byte) RecorderOpts {
+	return func(o *RecorderOptions) {
+		o.RedactBody = redact
+	}
+}
+
+// Recorder - http.RoundTripper recording interactions to a cassette and replaying them, safe for concurrent use.
+// Replayed interactions are used once each, in recorded order, so sequences such as 503, 503, 200 replay deterministically.
+type Recorder struct {
+	path     string
+	mode     Mode
+	options  RecorderOptions
+	mu       sync.Mutex
+	cassette *Cassette
+	used     map[*Interaction]bool
+	changed  bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// NewRecorder - recorder for the cassette at path. Replay and record-missing load the existing cassette, replay requires it to exist.
+// Call Save once the test completes to write recorded interactions.
+//
+// Example:
+//
+//	recorder, err := fetchtest.NewRecorder("testdata/users.yaml", fetchtest.ModeRecordMissing, fetchtest.WithRedactHeaders("Authorization"))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer recorder.Save()
+//
+//	client := fetch.New(fetch.WithOpts(fetch.WithHTTPClient(recorder.Client())))
+func NewRecorder(path string, mode Mode, opts ...RecorderOpts) (*Recorder, error) {
+	options := RecorderOptions{
+		Transport: http.DefaultTransport,
+		Matcher:   DefaultMatcher,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	recorder := &Recorder{
+		path:     path,
+		mode:     mode,
+		options:  options,
+		cassette: &Cassette{},
+		used:     map[*Interaction]bool{},
+	}
+
+	switch mode {
+	case ModeRecord, ModePassthrough:
+		return recorder, nil
+	case ModeReplay, ModeRecordMissing:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMode, mode)
+	}
+
+	cassette, err := LoadCassette(path)
+	switch {
+	case err == nil:
+		recorder.cassette = cassette
+	case errors.Is(err, os.ErrNotExist) && mode == ModeRecordMissing:
+	default:
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+// Client - http client sending every request through the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Mode - recorder mode
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Interactions - interactions in the cassette
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+// Save - write the cassette when interactions were recorded, the directory is created when missing
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.changed {
+		return nil
+	}
+
+	if err := SaveCassette(r.path, r.cassette); err != nil {
+		return err
+	}
+	r.changed = false
+
+	return nil
+}
+
+// RoundTrip - replay the matching interaction, or send and record the request depending on the mode
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModePassthrough {
+		return r.options.Transport.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	redacted := r.redactBody(body)
+
+	if r.mode != ModeRecord {
+		interaction, ok := r.take(req, redacted)
+		if ok {
+			return replay(req, interaction)
+		}
+
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+		}
+	}
+
+	return r.record(req, body, redacted)
+}
+
+// take - first unused interaction matching the request
+func (r *Recorder) take(req *http.Request, body []byte) (*Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, interaction := range r.cassette.Interactions {
+		if !r.used[interaction] && r.options.Matcher(req, body, interaction) {
+			r.used[interaction] = true
+			return interaction, true
+		}
+	}
+
+	return nil, false
+}
+
+// record - send the request and add the interaction to the cassette
+func (r *Recorder) record(req *http.Request, body, redacted []byte) (*http.Response, error) {
+	outgoing := req.Clone(req.Context())
+	if body != nil {
+		outgoing.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	// record readable bodies, a compressed response would be stored, and redacted, as encoded bytes
+	outgoing.Header.Del("Accept-Encoding")
+
+	resp, err := r.options.Transport.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: CassetteRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: r.redactHeaders(req.Header),
+		},
+		Response: CassetteResponse{
+			Status:  resp.StatusCode,
+			Headers: r.redactHeaders(resp.Header),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BodyEncoding = encodeBody(redacted)
+	interaction.Response.Body, interaction.Response.BodyEncoding = encodeBody(r.redactBody(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used[interaction] = true
+	r.changed = true
+
+	return resp, nil
+}
+
+func (r *Recorder) redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range r.options.RedactHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, Redacted)
+		}
+	}
+
+	return redacted
+}
+
+func (r *Recorder) redactBody(body []byte) []byte {
+	if r.options.RedactBody == nil || body == nil {
+		return body
+	}
+
+	return r.options.RedactBody(body)
+}
+
+// replay - response from the recorded interaction
+func replay(req *http.Request, interaction *Interaction) (*http.Response, error) {
+	body, err := interaction.Response.ResponseBody()
+	if err != nil {
+		return nil, err
+	}
+
+	status := interaction.Response.Status
+	header := interaction.Response.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// LoadCassette - read a YAML (.yaml / .yml) or JSON cassette
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &cassette)
+	} else {
+		err = json.Unmarshal(data, &cassette)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetchtest: decode cassette %s: %w", path, err)
+	}
+
+	return &cassette, nil
+}
+
+// SaveCassette - write a YAML (.yaml / .yml) or JSON cassette, the directory is created when missing
+func SaveCassette(path string, cassette *Cassette) error {
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(cassette)
+	} else {
+		data, err = json.MarshalIndent(cassette, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// readRequestBody - read and close the request body, nil when there is none
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+
+	return body, err
+}
+
+// encodeBody - the body as text, base64 encoded when it is not valid UTF-8
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), bodyBase64
+}
+
+func decodeBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case bodyBase64:
+		return base64.StdEncoding.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("fetchtest: unknown body encoding %s", encoding)
+	}
+}
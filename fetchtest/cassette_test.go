@@ -0,0 +1,226 @@
+package fetchtest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/fetch"
+	"github.com/code-gorilla-au/fetch/fetchtest"
+	"github.com/code-gorilla-au/odize"
+)
+
+func recordingClient(t *testing.T, path string, mode fetchtest.Mode, opts ...fetchtest.RecorderOpts) (*fetchtest.Recorder, *fetch.Client) {
+	t.Helper()
+
+	recorder, err := fetchtest.NewRecorder(path, mode, opts...)
+	odize.AssertNoError(t, err)
+
+	return recorder, fetch.New(fetch.WithOpts(
+		fetch.WithHTTPClient(recorder.Client()),
+		fetch.WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}),
+	))
+}
+
+func TestRecorder(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var upstream *fetchtest.Transport
+	var dir string
+
+	group.BeforeEach(func() {
+		upstream = fetchtest.NewTransport()
+		dir = t.TempDir()
+	})
+
+	err := group.
+		Test("should record a YAML cassette and replay it offline in order", func(t *testing.T) {
+			path := filepath.Join(dir, "cassettes", "users.yaml")
+			upstream.On(http.MethodGet, "/users").
+				Reply(http.StatusServiceUnavailable, "busy").
+				Reply(http.StatusServiceUnavailable, "busy").
+				ReplyJSON(http.StatusOK, []string{"ada"})
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecord, fetchtest.WithRecorderTransport(upstream))
+			resp, err := client.Get("http://api.test/users", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `["ada"]`, readBody(t, resp))
+			odize.AssertEqual(t, 3, len(recorder.Interactions()))
+			odize.AssertNoError(t, recorder.Save())
+
+			offline := fetchtest.NewTransport()
+			replayer, client := recordingClient(t, path, fetchtest.ModeReplay, fetchtest.WithRecorderTransport(offline))
+			resp, err = client.Get("http://api.test/users", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `["ada"]`, readBody(t, resp))
+			odize.AssertEqual(t, "application/json", resp.Header.Get("Content-Type"))
+			odize.AssertEqual(t, 0, len(offline.Requests()))
+
+			_, err = client.Get("http://api.test/users", nil)
+			odize.AssertTrue(t, errors.Is(err, fetchtest.ErrInteractionNotFound))
+			odize.AssertNoError(t, replayer.Save())
+		}).
+		Test("should round trip binary bodies through a JSON cassette", func(t *testing.T) {
+			path := filepath.Join(dir, "binary.json")
+			payload := []byte{0xff, 0x00, 0xfe, 0x01}
+			upstream.On(http.MethodPost, "/blob").ReplyWith(fetchtest.Response{Status: http.StatusOK, Body: payload})
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecord, fetchtest.WithRecorderTransport(upstream))
+			resp, err := client.Post("http://api.test/blob", bytes.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			_ = readBody(t, resp)
+			odize.AssertNoError(t, recorder.Save())
+
+			cassette, err := fetchtest.LoadCassette(path)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "base64", cassette.Interactions[0].Response.BodyEncoding)
+
+			_, client = recordingClient(t, path, fetchtest.ModeReplay)
+			resp, err = client.Post("http://api.test/blob", bytes.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, string(payload), readBody(t, resp))
+		}).
+		Test("should redact headers and bodies in the cassette", func(t *testing.T) {
+			path := filepath.Join(dir, "secret.yaml")
+			upstream.On(http.MethodPost, "/login").
+				ReplyWith(fetchtest.Response{Status: http.StatusOK, Headers: map[string]string{"Set-Cookie": "session=s3cr3t"}, Body: []byte(`{"token":"s3cr3t"}`)})
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecord,
+				fetchtest.WithRecorderTransport(upstream),
+				fetchtest.WithRedactHeaders("Authorization", "Set-Cookie"),
+				fetchtest.WithRedactBody(func(body []byte) []byte {
+					return bytes.ReplaceAll(body, []byte("s3cr3t"), []byte("xxx"))
+				}),
+			)
+			resp, err := client.Post("http://api.test/login", strings.NewReader(`{"password":"s3cr3t"}`), map[string]string{"Authorization": "Bearer s3cr3t"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `{"token":"s3cr3t"}`, readBody(t, resp))
+			odize.AssertNoError(t, recorder.Save())
+
+			data, err := os.ReadFile(path)
+			odize.AssertNoError(t, err)
+			odize.AssertFalse(t, strings.Contains(string(data), "s3cr3t"))
+			odize.AssertTrue(t, strings.Contains(string(data), fetchtest.Redacted))
+
+			_, client = recordingClient(t, path, fetchtest.ModeReplay,
+				fetchtest.WithMatcher(fetchtest.MatchBody),
+				fetchtest.WithRedactBody(func(body []byte) []byte {
+					return bytes.ReplaceAll(body, []byte("s3cr3t"), []byte("xxx"))
+				}),
+			)
+			resp, err = client.Post("http://api.test/login", strings.NewReader(`{"password":"s3cr3t"}`), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `{"token":"xxx"}`, readBody(t, resp))
+		}).
+		Test("should redact bodies of servers compressing responses", func(t *testing.T) {
+			path := filepath.Join(dir, "compressed.yaml")
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+					_, _ = w.Write([]byte(`{"token":"s3cr3t"}`))
+					return
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				_, _ = gz.Write([]byte(`{"token":"s3cr3t"}`))
+				_ = gz.Close()
+			}))
+			t.Cleanup(srv.Close)
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecord,
+				fetchtest.WithRecorderTransport(&http.Transport{DisableCompression: true}),
+				fetchtest.WithRedactBody(func(body []byte) []byte {
+					return bytes.ReplaceAll(body, []byte("s3cr3t"), []byte("xxx"))
+				}),
+			)
+			resp, err := client.Get(srv.URL+"/token", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `{"token":"s3cr3t"}`, readBody(t, resp))
+			odize.AssertNoError(t, recorder.Save())
+
+			data, err := os.ReadFile(path)
+			odize.AssertNoError(t, err)
+			odize.AssertFalse(t, strings.Contains(string(data), "s3cr3t"))
+			odize.AssertTrue(t, strings.Contains(string(data), `{"token":"xxx"}`))
+		}).
+		Test("should replay recorded interactions and record missing ones", func(t *testing.T) {
+			path := filepath.Join(dir, "missing.yaml")
+			users := upstream.On(http.MethodGet, "/users").Reply(http.StatusOK, "users")
+			orders := upstream.On(http.MethodGet, "/orders").Reply(http.StatusOK, "orders")
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecordMissing, fetchtest.WithRecorderTransport(upstream))
+			resp, err := client.Get("http://api.test/users", nil)
+			odize.AssertNoError(t, err)
+			_ = readBody(t, resp)
+			odize.AssertNoError(t, recorder.Save())
+
+			recorder, client = recordingClient(t, path, fetchtest.ModeRecordMissing, fetchtest.WithRecorderTransport(upstream))
+			for _, url := range []string{"http://api.test/users", "http://api.test/orders"} {
+				resp, err = client.Get(url, nil)
+				odize.AssertNoError(t, err)
+				_ = readBody(t, resp)
+			}
+			odize.AssertNoError(t, recorder.Save())
+
+			odize.AssertEqual(t, 1, users.Calls())
+			odize.AssertEqual(t, 1, orders.Calls())
+
+			cassette, err := fetchtest.LoadCassette(path)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 2, len(cassette.Interactions))
+		}).
+		Test("should select interactions with the matcher", func(t *testing.T) {
+			path := filepath.Join(dir, "search.yaml")
+			upstream.On(http.MethodPost, "/search").WithBody(func(b []byte) bool { return string(b) == "go" }).Reply(http.StatusOK, "gophers")
+			upstream.On(http.MethodPost, "/search").Reply(http.StatusOK, "crabs")
+
+			recorder, client := recordingClient(t, path, fetchtest.ModeRecord, fetchtest.WithRecorderTransport(upstream))
+			for _, query := range []string{"go", "rust"} {
+				resp, err := client.Post("http://api.test/search", strings.NewReader(query), nil)
+				odize.AssertNoError(t, err)
+				_ = readBody(t, resp)
+			}
+			odize.AssertNoError(t, recorder.Save())
+
+			_, client = recordingClient(t, path, fetchtest.ModeReplay, fetchtest.WithMatcher(fetchtest.MatchBody))
+			resp, err := client.Post("http://api.test/search", strings.NewReader("rust"), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "crabs", readBody(t, resp))
+		}).
+		Test("should require the cassette when replaying", func(t *testing.T) {
+			_, err := fetchtest.NewRecorder(filepath.Join(dir, "absent.yaml"), fetchtest.ModeReplay)
+			odize.AssertTrue(t, errors.Is(err, os.ErrNotExist))
+
+			_, err = fetchtest.NewRecorder(filepath.Join(dir, "absent.yaml"), fetchtest.Mode(42))
+			odize.AssertTrue(t, errors.Is(err, fetchtest.ErrInvalidMode))
+		}).
+		Test("should pass requests through without recording", func(t *testing.T) {
+			path := filepath.Join(dir, "passthrough.yaml")
+			upstream.On(http.MethodGet, "/live").Reply(http.StatusOK, "live")
+
+			recorder, client := recordingClient(t, path, fetchtest.ModePassthrough, fetchtest.WithRecorderTransport(upstream))
+			resp, err := client.Get("http://api.test/live", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "live", readBody(t, resp))
+			odize.AssertNoError(t, recorder.Save())
+
+			_, err = os.Stat(path)
+			odize.AssertTrue(t, errors.Is(err, os.ErrNotExist))
+		}).
+		Test("should parse mode names", func(t *testing.T) {
+			mode, err := fetchtest.ParseMode("Record-Missing")
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, fetchtest.ModeRecordMissing, mode)
+
+			_, err = fetchtest.ParseMode("rewind")
+			odize.AssertTrue(t, errors.Is(err, fetchtest.ErrInvalidMode))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
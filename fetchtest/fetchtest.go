@@ -0,0 +1,211 @@
+// Package fetchtest provides a programmable fake transport and server for testing code built on fetch.
+// Routes match requests by method, path, query and headers, and reply from an ordered script of responses.
+// Every request is recorded, including its body.
+package fetchtest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoRoute - no route matched the request
+var ErrNoRoute = errors.New("fetchtest: no route matches the request")
+
+// TB - the subset of testing.TB used by the assertions
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// RecordedRequest - a request received by the transport
+type RecordedRequest struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+	// Route matching the request, nil when unmatched
+	Route *Route
+	// Time the request was received
+	Time time.Time
+}
+
+// Transport - fake http.RoundTripper and http.Handler replying from scripted routes, safe for concurrent use
+type Transport struct {
+	mu       sync.Mutex
+	routes   []*Route
+	requests []*RecordedRequest
+}
+
+var (
+	_ http.RoundTripper = (*Transport)(nil)
+	_ http.Handler      = (*Transport)(nil)
+)
+
+// NewTransport - returns a transport without routes
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// Client - http client sending every request to the transport, e.g. fetch.WithHTTPClient(transport.Client())
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// Server - start a test server replying from the routes, closed by the caller
+func (t *Transport) Server() *httptest.Server {
+	return httptest.NewServer(t)
+}
+
+// On - add a route matching the method and path. The path may contain path.Match patterns, e.g. /users/*.
+// Routes are matched in the order they were added.
+func (t *Transport) On(method, path string) *Route {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	route := &Route{
+		method:  strings.ToUpper(method),
+		path:    path,
+		query:   url.Values{},
+		headers: http.Header{},
+		mu:      &t.mu,
+	}
+	t.routes = append(t.routes, route)
+
+	return route
+}
+
+// RoundTrip - reply with the next scripted response of the first matching route
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := record(req)
+	if err != nil {
+		return nil, err
+	}
+
+	route, response := t.match(recorded)
+	if route == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoRoute, req.Method, req.URL)
+	}
+
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	if response.Err != nil {
+		return nil, response.Err
+	}
+
+	return response.httpResponse(req), nil
+}
+
+// ServeHTTP - reply with the next scripted response of the first matching route, unmatched requests receive 501 Not Implemented
+func (t *Transport) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrNoRoute) {
+			status = http.StatusNotImplemented
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// Requests - every request received, in order
+func (t *Transport) Requests() []*RecordedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]*RecordedRequest(nil), t.requests...)
+}
+
+// Unmatched - requests no route matched
+func (t *Transport) Unmatched() []*RecordedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unmatched []*RecordedRequest
+	for _, request := range t.requests {
+		if request.Route == nil {
+			unmatched = append(unmatched, request)
+		}
+	}
+
+	return unmatched
+}
+
+// AssertExpectations - report unmatched requests, and routes called fewer times than expected
+func (t *Transport) AssertExpectations(tb TB) {
+	tb.Helper()
+
+	for _, request := range t.Unmatched() {
+		tb.Errorf("fetchtest: unmatched request %s %s", request.Method, request.URL)
+	}
+
+	t.mu.Lock()
+	routes := append([]*Route(nil), t.routes...)
+	t.mu.Unlock()
+
+	for _, route := range routes {
+		if calls, expected := route.Calls(), route.expectedCalls(); calls < expected {
+			tb.Errorf("fetchtest: route %s expected %d calls, received %d", route, expected, calls)
+		}
+	}
+}
+
+// match - find the route for the request and take its next response
+func (t *Transport) match(recorded *RecordedRequest) (*Route, Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.requests = append(t.requests, recorded)
+
+	for _, route := range t.routes {
+		if route.matches(recorded) {
+			recorded.Route = route
+			return route, route.next(recorded)
+		}
+	}
+
+	return nil, Response{}
+}
+
+// record - capture the request, reading and closing the body
+func record(req *http.Request) (*RecordedRequest, error) {
+	recorded := &RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Time:   time.Now(),
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded.Body = body
+
+	return recorded, nil
+}
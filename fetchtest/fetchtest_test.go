@@ -0,0 +1,194 @@
+package fetchtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/fetch"
+	"github.com/code-gorilla-au/fetch/fetchtest"
+	"github.com/code-gorilla-au/odize"
+)
+
+// recorderTB - captures assertion failures
+type recorderTB struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recorderTB) Helper() {}
+
+func (r *recorderTB) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	odize.AssertNoError(t, err)
+
+	return string(body)
+}
+
+func TestTransport(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var transport *fetchtest.Transport
+	var client *fetch.Client
+
+	group.BeforeEach(func() {
+		transport = fetchtest.NewTransport()
+		client = fetch.New(fetch.WithOpts(
+			fetch.WithHTTPClient(transport.Client()),
+			fetch.WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}),
+		))
+	})
+
+	err := group.
+		Test("should reply from the script in order and retry through 503s", func(t *testing.T) {
+			route := transport.On(http.MethodGet, "/users").
+				Reply(http.StatusServiceUnavailable, "busy").
+				Reply(http.StatusServiceUnavailable, "busy").
+				Reply(http.StatusOK, "ok")
+
+			resp, err := client.Get("http://api.test/users", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, http.StatusOK, resp.StatusCode)
+			odize.AssertEqual(t, "ok", readBody(t, resp))
+			odize.AssertEqual(t, 3, route.Calls())
+
+			transport.AssertExpectations(t)
+		}).
+		Test("should repeat the last response once the script is exhausted", func(t *testing.T) {
+			transport.On(http.MethodGet, "/ping").Reply(http.StatusAccepted, "first").Reply(http.StatusOK, "last")
+
+			for _, expected := range []string{"first", "last", "last"} {
+				resp, err := client.Get("http://api.test/ping", nil)
+				odize.AssertNoError(t, err)
+				odize.AssertEqual(t, expected, readBody(t, resp))
+			}
+		}).
+		Test("should match by query, header and path pattern", func(t *testing.T) {
+			admin := transport.On(http.MethodGet, "/users/*").WithHeader("X-Role", "admin").Reply(http.StatusOK, "admin")
+			paged := transport.On(http.MethodGet, "/users/*").WithQuery("page", "2").Reply(http.StatusOK, "page two")
+			fallback := transport.On(http.MethodGet, "/users/*").Reply(http.StatusOK, "user")
+
+			resp, err := client.Get("http://api.test/users/1", map[string]string{"X-Role": "admin"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "admin", readBody(t, resp))
+
+			resp, err = client.Get("http://api.test/users/1?page=2", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "page two", readBody(t, resp))
+
+			resp, err = client.Get("http://api.test/users/1", nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "user", readBody(t, resp))
+
+			odize.AssertEqual(t, 1, admin.Calls())
+			odize.AssertEqual(t, 1, paged.Calls())
+			odize.AssertEqual(t, 1, fallback.Calls())
+		}).
+		Test("should record requests with their bodies", func(t *testing.T) {
+			route := transport.On(http.MethodPost, "/users").ReplyJSON(http.StatusCreated, map[string]string{"id": "1"})
+
+			resp, err := client.Post("http://api.test/users", strings.NewReader(`{"name":"ada"}`), map[string]string{"Content-Type": "application/json"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, `{"id":"1"}`, readBody(t, resp))
+			odize.AssertEqual(t, "application/json", resp.Header.Get("Content-Type"))
+
+			requests := route.Requests()
+			odize.AssertEqual(t, 1, len(requests))
+			odize.AssertEqual(t, `{"name":"ada"}`, string(requests[0].Body))
+			odize.AssertEqual(t, "application/json", requests[0].Header.Get("Content-Type"))
+			odize.AssertEqual(t, requests, transport.Requests())
+		}).
+		Test("should match on the request body", func(t *testing.T) {
+			transport.On(http.MethodPost, "/search").
+				WithBody(func(body []byte) bool { return strings.Contains(string(body), "go") }).
+				Reply(http.StatusOK, "found")
+			transport.On(http.MethodPost, "/search").Reply(http.StatusNotFound, "")
+
+			resp, err := client.Post("http://api.test/search", strings.NewReader("golang"), nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "found", readBody(t, resp))
+
+			resp, err = client.Post("http://api.test/search", strings.NewReader("rust"), nil)
+			odize.AssertError(t, err)
+			odize.AssertEqual(t, http.StatusNotFound, resp.StatusCode)
+			_ = readBody(t, resp)
+		}).
+		Test("should fail the round trip with scripted transport faults", func(t *testing.T) {
+			transport.On(http.MethodGet, "/flaky").Fail(syscall.ECONNRESET)
+
+			_, err := client.Get("http://api.test/flaky", nil)
+			odize.AssertTrue(t, errors.Is(err, syscall.ECONNRESET))
+		}).
+		Test("should delay the response until the request context is done", func(t *testing.T) {
+			transport.On(http.MethodGet, "/slow").Reply(http.StatusOK, "late").Delay(time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := client.GetCtx(ctx, "http://api.test/slow", nil)
+			odize.AssertError(t, err)
+			odize.AssertTrue(t, time.Since(start) < 500*time.Millisecond)
+		}).
+		Test("should report unmatched requests", func(t *testing.T) {
+			transport.On(http.MethodGet, "/known").Optional()
+
+			_, err := client.Get("http://api.test/unknown", nil)
+			odize.AssertTrue(t, errors.Is(err, fetchtest.ErrNoRoute))
+			odize.AssertEqual(t, 1, len(transport.Unmatched()))
+
+			tb := &recorderTB{}
+			transport.AssertExpectations(tb)
+			odize.AssertEqual(t, 1, len(tb.errors))
+			odize.AssertTrue(t, strings.Contains(tb.errors[0], "unmatched request GET http://api.test/unknown"))
+		}).
+		Test("should report routes called fewer times than expected", func(t *testing.T) {
+			transport.On(http.MethodGet, "/twice").Reply(http.StatusOK, "")
+			transport.On(http.MethodDelete, "/never").Times(1)
+			transport.On(http.MethodGet, "/scripted").Reply(http.StatusServiceUnavailable, "").Reply(http.StatusOK, "")
+
+			resp, err := client.Get("http://api.test/twice", nil)
+			odize.AssertNoError(t, err)
+			_ = readBody(t, resp)
+
+			tb := &recorderTB{}
+			transport.AssertExpectations(tb)
+			odize.AssertEqual(t, []string{
+				"fetchtest: route DELETE /never expected 1 calls, received 0",
+				"fetchtest: route GET /scripted expected 2 calls, received 0",
+			}, tb.errors)
+		}).
+		Test("should serve routes over a test server", func(t *testing.T) {
+			transport.On(http.MethodGet, "/served").Reply(http.StatusTeapot, "short and stout")
+			srv := transport.Server()
+			defer srv.Close()
+
+			resp, err := fetch.New(&fetch.Options{}).Get(srv.URL+"/served", nil)
+			odize.AssertError(t, err)
+			odize.AssertEqual(t, http.StatusTeapot, resp.StatusCode)
+			odize.AssertEqual(t, "short and stout", readBody(t, resp))
+
+			resp, err = fetch.New(&fetch.Options{}).Get(srv.URL+"/missing", nil)
+			odize.AssertError(t, err)
+			odize.AssertEqual(t, http.StatusNotImplemented, resp.StatusCode)
+			_ = readBody(t, resp)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
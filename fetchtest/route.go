@@ -0,0 +1,234 @@
+package fetchtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response - a scripted response
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+	// Wait before replying, cut short when the request context is done
+	Delay time.Duration
+	// Fail the round trip with the error instead of replying, e.g. a connection reset
+	Err error
+}
+
+// Route - matches requests and replies from an ordered script of responses. Once the script is exhausted the last response repeats.
+// Routes without responses reply 200 OK with an empty body.
+type Route struct {
+	method   string
+	path     string
+	query    url.Values
+	headers  http.Header
+	body     func([]byte) bool
+	script   []Response
+	times    int
+	optional bool
+	requests []*RecordedRequest
+	// guards requests, shared with the transport
+	mu *sync.Mutex
+}
+
+// WithQuery - only match requests with the query parameter
+func (r *Route) WithQuery(key, value string) *Route {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithHeader - only match requests with the header
+func (r *Route) WithHeader(key, value string) *Route {
+	r.headers.Add(key, value)
+	return r
+}
+
+// WithBody - only match requests whose body satisfies match
+func (r *Route) WithBody(match func(body []byte) bool) *Route {
+	r.body = match
+	return r
+}
+
+// Reply - add a response to the script
+func (r *Route) Reply(status int, body string) *Route {
+	return r.ReplyWith(Response{Status: status, Body: []byte(body)})
+}
+
+// ReplyJSON - add a JSON response to the script, panics if v cannot be encoded
+func (r *Route) ReplyJSON(status int, v any) *Route {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("fetchtest: encode reply: %s", err))
+	}
+
+	return r.ReplyWith(Response{
+		Status:  status,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	})
+}
+
+// ReplyWith - add a response to the script
+func (r *Route) ReplyWith(response Response) *Route {
+	r.script = append(r.script, response)
+	return r
+}
+
+// Fail - add a transport error to the script
+func (r *Route) Fail(err error) *Route {
+	return r.ReplyWith(Response{Err: err})
+}
+
+// Delay - delay the last scripted response
+func (r *Route) Delay(d time.Duration) *Route {
+	if len(r.script) == 0 {
+		r.script = append(r.script, Response{Status: http.StatusOK})
+	}
+	r.script[len(r.script)-1].Delay = d
+
+	return r
+}
+
+// Times - expect the route to be called at least n times, default is once per scripted response
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+// Optional - do not expect the route to be called
+func (r *Route) Optional() *Route {
+	r.optional = true
+	return r
+}
+
+// Calls - number of requests matched by the route
+func (r *Route) Calls() int {
+	return len(r.Requests())
+}
+
+// Requests - requests matched by the route, in order
+func (r *Route) Requests() []*RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*RecordedRequest(nil), r.requests...)
+}
+
+func (r *Route) String() string {
+	var builder strings.Builder
+	builder.WriteString(r.method + " " + r.path)
+	if len(r.query) > 0 {
+		builder.WriteString("?" + r.query.Encode())
+	}
+
+	return builder.String()
+}
+
+// expectedCalls - minimum number of calls
+func (r *Route) expectedCalls() int {
+	switch {
+	case r.optional:
+		return 0
+	case r.times > 0:
+		return r.times
+	case len(r.script) > 0:
+		return len(r.script)
+	default:
+		return 1
+	}
+}
+
+// matches - the request matches the method, path, query, headers and body
+func (r *Route) matches(req *RecordedRequest) bool {
+	if r.method != "" && r.method != "*" && r.method != req.Method {
+		return false
+	}
+
+	if ok, err := path.Match(r.path, req.URL.Path); err != nil || !ok {
+		return false
+	}
+
+	query := req.URL.Query()
+	for key, values := range r.query {
+		for _, value := range values {
+			if !contains(query[key], value) {
+				return false
+			}
+		}
+	}
+
+	for key, values := range r.headers {
+		for _, value := range values {
+			if !contains(req.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+
+	return r.body == nil || r.body(req.Body)
+}
+
+// next - record the request and take the next scripted response, callers must hold the transport lock
+func (r *Route) next(req *RecordedRequest) Response {
+	r.requests = append(r.requests, req)
+
+	if len(r.script) == 0 {
+		return Response{Status: http.StatusOK}
+	}
+
+	index := len(r.requests) - 1
+	if index >= len(r.script) {
+		index = len(r.script) - 1
+	}
+
+	return r.script[index]
+}
+
+// httpResponse - the scripted response for the request
+func (s Response) httpResponse(req *http.Request) *http.Response {
+	status := s.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	header := http.Header{}
+	for key, value := range s.Headers {
+		header.Set(key, value)
+	}
+	if header.Get("Content-Length") == "" {
+		header.Set("Content-Length", strconv.Itoa(len(s.Body)))
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(s.Body)),
+		ContentLength: int64(len(s.Body)),
+		Request:       req,
+	}
+}
+
+// contains - values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,7 +2,12 @@ module github.com/code-gorilla-au/fetch
 
 go 1.25.3
 
-require github.com/code-gorilla-au/odize v1.3.4
+require (
+	github.com/andybalholm/brotli v1.2.6
+	github.com/code-gorilla-au/odize v1.3.4
+	github.com/klauspost/compress v1.20.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/code-gorilla-au/env v1.1.1 // indirect
@@ -0,0 +1,578 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// HARVersion - HTTP Archive format version written by the recorder
+const HARVersion = "1.2"
+
+// HAR - HTTP Archive, loadable in browser devtools
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog - root of the archive
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator - application that created the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry - a single attempt. Attempts of the same call share CallID, retries have Attempt > 1
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	// Call the attempt belongs to, shared by its retries
+	CallID int64 `json:"_callId"`
+	// Attempt number within the call, starting at 1
+	Attempt int `json:"_attempt"`
+	// Transport error of the attempt, the response is empty
+	Error string `json:"_error,omitempty"`
+}
+
+// HARRequest - request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse - response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue - header or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie - request or response cookie
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// HARPostData - request body, Text is cut off at the recorder's body limit
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent - decoded response body, Text is cut off at the recorder's body limit
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings - phases of the attempt in milliseconds, -1 when the phase does not apply
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder - records every attempt a client sends, safe for concurrent use.
+// Responses served from the cache, conditional store or a coalesced request are not sent, so they are not recorded.
+// Headers are recorded as sent, including credentials.
+type HARRecorder struct {
+	maxBodySize int64
+	calls       atomic.Int64
+	mu          sync.Mutex
+	entries     []*harEntry
+}
+
+// NewHARRecorder - recorder capturing request and response bodies up to maxBodySize bytes each, bodies are not captured when maxBodySize <= 0
+func NewHARRecorder(maxBodySize int64) *HARRecorder {
+	return &HARRecorder{maxBodySize: maxBodySize}
+}
+
+// WithHAR - record every attempt to the HAR recorder
+func WithHAR(recorder *HARRecorder) FnOpts {
+	return func(o *Options) error {
+		o.HAR = recorder
+		return nil
+	}
+}
+
+// HAR - snapshot of the recorded entries
+func (h *HARRecorder) HAR() *HAR {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]HAREntry, 0, len(h.entries))
+	for _, entry := range h.entries {
+		entries = append(entries, entry.snapshot())
+	}
+
+	return &HAR{Log: HARLog{
+		Version: HARVersion,
+		Creator: HARCreator{Name: "fetch", Version: moduleVersion()},
+		Entries: entries,
+	}}
+}
+
+// modulePath - import path of the fetch module
+const modulePath = "github.com/code-gorilla-au/fetch"
+
+// moduleVersion - version of the fetch module from the build info, empty when unknown such as in development builds
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if info.Main.Path == modulePath && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+
+	return ""
+}
+
+// WriteTo - write the archive as JSON
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(h.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile - write the archive as JSON to the file
+func (h *HARRecorder) WriteFile(path string) error {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = h.WriteTo(file)
+	return errors.Join(err, file.Close())
+}
+
+// Reset - discard the recorded entries
+func (h *HARRecorder) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = nil
+}
+
+// harCallKey - context key holding the call id
+type harCallKey struct{}
+
+// withHARCall - assign a call id shared by the attempts of the call
+func (a *Client) withHARCall(ctx context.Context) context.Context {
+	if a.HAR == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, harCallKey{}, a.HAR.calls.Add(1))
+}
+
+// harEntry - an attempt being recorded, fields are guarded by the recorder lock
+type harEntry struct {
+	recorder *HARRecorder
+	entry    HAREntry
+	request  *harBodyCapture
+	response *harBodyCapture
+	// phase boundaries reported by httptrace
+	start, dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone time.Time
+	gotConn, wroteRequest, firstByte, end                                  time.Time
+}
+
+// begin - start recording the attempt, returns the request with tracing enabled. Nil when not recording
+func (h *HARRecorder) begin(ctx context.Context, req *http.Request) (*http.Request, *harEntry) {
+	if h == nil {
+		return req, nil
+	}
+
+	callID, _ := ctx.Value(harCallKey{}).(int64)
+	e := &harEntry{recorder: h, start: time.Now()}
+	e.entry = HAREntry{
+		StartedDateTime: e.start,
+		CallID:          callID,
+		Attempt:         attemptOf(ctx),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     harCookies(req.Cookies()),
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req),
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Response: HARResponse{
+			Cookies:     []HARCookie{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		e.request = &harBodyCapture{limit: h.maxBodySize}
+		e.entry.Request.PostData = &HARPostData{MimeType: req.Header.Get("Content-Type")}
+		req.Body = &harBody{ReadCloser: req.Body, entry: e, capture: e.request}
+	}
+
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), e.trace())), e
+}
+
+// trace - record the phase boundaries of the attempt
+func (e *harEntry) trace() *httptrace.ClientTrace {
+	at := func(t *time.Time) {
+		e.recorder.mu.Lock()
+		defer e.recorder.mu.Unlock()
+		*t = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { at(&e.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { at(&e.dnsDone) },
+		ConnectStart:      func(string, string) { at(&e.connectStart) },
+		ConnectDone:       func(string, string, error) { at(&e.connectDone) },
+		TLSHandshakeStart: func() { at(&e.tlsStart) },
+		TLSHandshakeDone:  func(_ tls.ConnectionState, _ error) { at(&e.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			at(&e.gotConn)
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				e.recorder.mu.Lock()
+				e.entry.ServerIPAddress = addr.IP.String()
+				e.recorder.mu.Unlock()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { at(&e.wroteRequest) },
+		GotFirstResponseByte: func() { at(&e.firstByte) },
+	}
+}
+
+// fail - record the transport error of the attempt
+func (e *harEntry) fail(err error) {
+	if e == nil {
+		return
+	}
+
+	e.recorder.mu.Lock()
+	defer e.recorder.mu.Unlock()
+
+	e.entry.Error = err.Error()
+	if e.end.IsZero() {
+		e.end = time.Now()
+	}
+}
+
+// respond - record the status and headers, the body is captured by capture
+func (e *harEntry) respond(resp *http.Response) {
+	if e == nil {
+		return
+	}
+
+	e.recorder.mu.Lock()
+	defer e.recorder.mu.Unlock()
+
+	if e.firstByte.IsZero() {
+		e.firstByte = time.Now()
+	}
+
+	e.entry.Request.HTTPVersion = resp.Proto
+	e.entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     harCookies(resp.Cookies()),
+		Headers:     harHeaders(resp.Header),
+		Content:     HARContent{MimeType: resp.Header.Get("Content-Type")},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    resp.ContentLength,
+	}
+}
+
+// capture - record the decoded response body as it is read, the attempt ends once the body is read or closed
+func (e *harEntry) capture(resp *http.Response) {
+	if e == nil {
+		return
+	}
+
+	e.recorder.mu.Lock()
+	defer e.recorder.mu.Unlock()
+
+	if resp.Body == nil || resp.Body == http.NoBody {
+		e.entry.Response.Content.Size = 0
+		e.end = time.Now()
+		return
+	}
+
+	e.response = &harBodyCapture{limit: e.recorder.maxBodySize}
+	resp.Body = &harBody{ReadCloser: resp.Body, entry: e, capture: e.response, response: true}
+}
+
+// snapshot - entry with the captured bodies and timings, callers must hold the recorder lock
+func (e *harEntry) snapshot() HAREntry {
+	entry := e.entry
+
+	if e.request != nil && entry.Request.PostData != nil {
+		postData := *entry.Request.PostData
+		postData.Text, postData.Encoding = e.request.text()
+		postData.Comment = e.request.comment()
+		entry.Request.BodySize = e.request.size
+		entry.Request.PostData = &postData
+	}
+
+	if e.response != nil {
+		entry.Response.Content.Size = e.response.size
+		entry.Response.Content.Text, entry.Response.Content.Encoding = e.response.text()
+		entry.Response.Content.Comment = e.response.comment()
+	}
+
+	entry.Timings = e.timings()
+	entry.Time = 0
+	for _, phase := range []float64{entry.Timings.Blocked, entry.Timings.DNS, entry.Timings.Connect, entry.Timings.Send, entry.Timings.Wait, entry.Timings.Receive} {
+		if phase > 0 {
+			entry.Time += phase
+		}
+	}
+
+	return entry
+}
+
+// timings - HAR phases from the trace, phases without trace events fall back to the surrounding boundaries
+func (e *harEntry) timings() HARTimings {
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+
+	if !e.dnsStart.IsZero() && !e.dnsDone.IsZero() {
+		timings.DNS = milliseconds(e.dnsDone.Sub(e.dnsStart))
+	}
+	if !e.tlsStart.IsZero() && !e.tlsDone.IsZero() {
+		timings.SSL = milliseconds(e.tlsDone.Sub(e.tlsStart))
+	}
+	if !e.connectStart.IsZero() && !e.connectDone.IsZero() {
+		connectEnd := e.connectDone
+		if e.tlsDone.After(connectEnd) {
+			connectEnd = e.tlsDone
+		}
+		timings.Connect = milliseconds(connectEnd.Sub(e.connectStart))
+	}
+
+	sendStart := e.start
+	if !e.gotConn.IsZero() {
+		sendStart = e.gotConn
+		blocked := milliseconds(e.gotConn.Sub(e.start)) - max(timings.DNS, 0) - max(timings.Connect, 0)
+		timings.Blocked = max(blocked, 0)
+	}
+
+	sendEnd := latest(sendStart, e.wroteRequest)
+	waitEnd := latest(sendEnd, e.firstByte)
+	receiveEnd := latest(waitEnd, e.end)
+
+	timings.Send = milliseconds(sendEnd.Sub(sendStart))
+	timings.Wait = milliseconds(waitEnd.Sub(sendEnd))
+	timings.Receive = milliseconds(receiveEnd.Sub(waitEnd))
+	if e.firstByte.IsZero() {
+		// no response, the time until the error is spent waiting
+		timings.Wait = milliseconds(receiveEnd.Sub(sendEnd))
+		timings.Receive = 0
+	}
+
+	return timings
+}
+
+// harBody - captures a request or response body as it is read
+type harBody struct {
+	io.ReadCloser
+	entry    *harEntry
+	capture  *harBodyCapture
+	response bool
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.entry.recorder.mu.Lock()
+	defer b.entry.recorder.mu.Unlock()
+
+	b.capture.write(p[:n])
+	if b.response && err != nil && b.entry.end.IsZero() {
+		b.entry.end = time.Now()
+	}
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	if b.response {
+		b.entry.recorder.mu.Lock()
+		if b.entry.end.IsZero() {
+			b.entry.end = time.Now()
+		}
+		b.entry.recorder.mu.Unlock()
+	}
+
+	return b.ReadCloser.Close()
+}
+
+// harBodyCapture - the first limit bytes of a body, and its total size
+type harBodyCapture struct {
+	limit int64
+	data  []byte
+	size  int64
+}
+
+func (c *harBodyCapture) write(p []byte) {
+	c.size += int64(len(p))
+	if remaining := c.limit - int64(len(c.data)); remaining > 0 {
+		c.data = append(c.data, p[:min(int64(len(p)), remaining)]...)
+	}
+}
+
+// text - captured body, base64 encoded when it is not valid UTF-8
+func (c *harBodyCapture) text() (string, string) {
+	if utf8.Valid(c.data) {
+		return string(c.data), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(c.data), "base64"
+}
+
+func (c *harBodyCapture) comment() string {
+	if int64(len(c.data)) < c.size {
+		return "truncated"
+	}
+
+	return ""
+}
+
+func harHeaders(header http.Header) []HARNameValue {
+	values := []HARNameValue{}
+	for name, list := range header {
+		for _, value := range list {
+			values = append(values, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sortNameValues(values)
+
+	return values
+}
+
+func harQuery(req *http.Request) []HARNameValue {
+	values := []HARNameValue{}
+	for name, list := range req.URL.Query() {
+		for _, value := range list {
+			values = append(values, HARNameValue{Name: name, Value: value})
+		}
+	}
+	sortNameValues(values)
+
+	return values
+}
+
+func harCookies(cookies []*http.Cookie) []HARCookie {
+	values := []HARCookie{}
+	for _, cookie := range cookies {
+		value := HARCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			HTTPOnly: cookie.HttpOnly,
+			Secure:   cookie.Secure,
+		}
+		if !cookie.Expires.IsZero() {
+			expires := cookie.Expires
+			value.Expires = &expires
+		}
+		values = append(values, value)
+	}
+
+	return values
+}
+
+func sortNameValues(values []HARNameValue) {
+	slices.SortStableFunc(values, func(a, b HARNameValue) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// latest - the later of the boundary and t, the boundary when t is not set
+func latest(boundary, t time.Time) time.Time {
+	if t.After(boundary) {
+		return t
+	}
+
+	return boundary
+}
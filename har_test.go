@@ -0,0 +1,175 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestClient_har(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var recorder *HARRecorder
+
+	group.BeforeEach(func() {
+		recorder = NewHARRecorder(16)
+	})
+
+	err := group.
+		Test("retries should be recorded as attempts of the same call", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, count int32) {
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("ok"))
+			})
+
+			client := New(WithOpts(WithHAR(recorder), WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+			resp, err := client.Get(srv.URL+"/users?page=2", map[string]string{"X-Trace": "abc"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "ok", readBody(t, resp))
+
+			har := recorder.HAR()
+			odize.AssertEqual(t, HARVersion, har.Log.Version)
+			odize.AssertEqual(t, HARCreator{Name: "fetch", Version: moduleVersion()}, har.Log.Creator)
+			odize.AssertTrue(t, har.Log.Creator.Version != HARVersion)
+
+			entries := har.Log.Entries
+			odize.AssertEqual(t, 2, len(entries))
+			odize.AssertEqual(t, entries[0].CallID, entries[1].CallID)
+			odize.AssertEqual(t, 1, entries[0].Attempt)
+			odize.AssertEqual(t, 2, entries[1].Attempt)
+			odize.AssertEqual(t, http.StatusServiceUnavailable, entries[0].Response.Status)
+			odize.AssertEqual(t, http.StatusOK, entries[1].Response.Status)
+
+			entry := entries[1]
+			odize.AssertEqual(t, http.MethodGet, entry.Request.Method)
+			odize.AssertEqual(t, []HARNameValue{{Name: "page", Value: "2"}}, entry.Request.QueryString)
+			odize.AssertTrue(t, containsNameValue(entry.Request.Headers, "X-Trace", "abc"))
+			odize.AssertEqual(t, "HTTP/1.1", entry.Response.HTTPVersion)
+			odize.AssertEqual(t, "ok", entry.Response.Content.Text)
+			odize.AssertEqual(t, int64(2), entry.Response.Content.Size)
+			odize.AssertEqual(t, "text/plain", entry.Response.Content.MimeType)
+			odize.AssertEqual(t, "127.0.0.1", entries[0].ServerIPAddress)
+
+			timings := entries[0].Timings
+			odize.AssertTrue(t, timings.Connect >= 0)
+			odize.AssertEqual(t, float64(-1), timings.SSL)
+			odize.AssertTrue(t, timings.Send >= 0 && timings.Wait >= 0 && timings.Receive >= 0)
+			odize.AssertTrue(t, entries[0].Time > 0)
+		}).
+		Test("bodies should be captured up to the limit", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(bytes.Repeat([]byte("r"), 100))
+			})
+
+			client := New(WithOpts(WithHAR(recorder)))
+			resp, err := client.Post(srv.URL, strings.NewReader(strings.Repeat("q", 40)), map[string]string{"Content-Type": "text/plain"})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 100, len(readBody(t, resp)))
+
+			entry := recorder.HAR().Log.Entries[0]
+			odize.AssertEqual(t, "text/plain", entry.Request.PostData.MimeType)
+			odize.AssertEqual(t, strings.Repeat("q", 16), entry.Request.PostData.Text)
+			odize.AssertEqual(t, "truncated", entry.Request.PostData.Comment)
+			odize.AssertEqual(t, int64(40), entry.Request.BodySize)
+			odize.AssertEqual(t, strings.Repeat("r", 16), entry.Response.Content.Text)
+			odize.AssertEqual(t, "truncated", entry.Response.Content.Comment)
+			odize.AssertEqual(t, int64(100), entry.Response.Content.Size)
+		}).
+		Test("decoded content should be recorded with the headers as sent", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(encode(t, "gzip", []byte("hello")))
+			})
+
+			client := New(WithOpts(WithHAR(recorder)))
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, "hello", readBody(t, resp))
+
+			entry := recorder.HAR().Log.Entries[0]
+			odize.AssertEqual(t, "hello", entry.Response.Content.Text)
+			odize.AssertTrue(t, containsNameValue(entry.Response.Headers, "Content-Encoding", "gzip"))
+		}).
+		Test("transport errors should be recorded", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+			url := srv.URL
+			srv.Close()
+
+			client := New(WithOpts(WithHAR(recorder)))
+			_, err := client.Get(url, nil)
+			odize.AssertError(t, err)
+
+			entry := recorder.HAR().Log.Entries[0]
+			odize.AssertTrue(t, entry.Error != "")
+			odize.AssertEqual(t, 0, entry.Response.Status)
+			odize.AssertEqual(t, float64(0), entry.Timings.Receive)
+		}).
+		Test("separate calls should have separate call ids", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+
+			client := New(WithOpts(WithHAR(recorder)))
+			for range 2 {
+				resp, err := client.GetCtx(context.Background(), srv.URL, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+			}
+
+			entries := recorder.HAR().Log.Entries
+			odize.AssertEqual(t, 2, len(entries))
+			odize.AssertFalse(t, entries[0].CallID == entries[1].CallID)
+
+			recorder.Reset()
+			odize.AssertEqual(t, 0, len(recorder.HAR().Log.Entries))
+		}).
+		Test("archive should be written as HAR 1.2 JSON", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+
+			client := New(WithOpts(WithHAR(recorder)))
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			path := filepath.Join(t.TempDir(), "traffic.har")
+			odize.AssertNoError(t, recorder.WriteFile(path))
+
+			data, err := os.ReadFile(path)
+			odize.AssertNoError(t, err)
+
+			var archive map[string]map[string]any
+			odize.AssertNoError(t, json.Unmarshal(data, &archive))
+			odize.AssertEqual(t, "1.2", archive["log"]["version"])
+
+			entries, ok := archive["log"]["entries"].([]any)
+			odize.AssertTrue(t, ok)
+			odize.AssertEqual(t, 1, len(entries))
+
+			var buf bytes.Buffer
+			n, err := recorder.WriteTo(&buf)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, int64(len(data)), n)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func containsNameValue(values []HARNameValue, name, value string) bool {
+	for _, v := range values {
+		if v.Name == name && v.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,6 +3,7 @@ package fetch
 import (
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -22,6 +23,19 @@ type httpClient interface {
 	Post(url string, contentType string, body io.Reader) (resp *http.Response, err error)
 }
 
+// Signer - signs an outgoing request. Called before every attempt, so retries are re-signed
+type Signer interface {
+	Sign(req *http.Request) error
+}
+
+// CacheStore - storage for cached responses, implementations must be safe for concurrent use.
+// Stored entries are treated as read only.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, entry *CachedResponse)
+	Delete(key string)
+}
+
 type Client struct {
 	// Retry backoff strategy.
 	// Default is 1s,3s,5s,10s
@@ -30,6 +44,61 @@ type Client struct {
 	Client httpClient
 	// Headers to be added to each request
 	DefaultHeaders map[string]string
+	// Sign each request attempt, default is none
+	Signer Signer
+	// Maximum duration of a single attempt, including reading the body, default is none
+	AttemptTimeout time.Duration
+	// Maximum duration of the whole call, including retries and backoff waits, default is none
+	Timeout time.Duration
+	// Cache GET responses, default is none
+	Cache CacheStore
+	// Store validators and bodies for automatic conditional GET requests, default is none
+	ConditionalStore CacheStore
+	// Share in flight identical GET / HEAD requests, default is none
+	Coalescer *Coalescer
+	// Keep compressed response bodies as sent by the server, default is to decode gzip, deflate, br and zstd
+	DisableDecompression bool
+	// Maximum decompressed size of a response body in bytes, default is DefaultMaxDecompressedSize
+	MaxDecompressedSize int64
+	// Compress request bodies, default is none
+	RequestCompression *RequestCompression
+	// Report upload and download progress, default is none
+	Progress ProgressFunc
+	// Minimum interval between progress reports, default is DefaultProgressInterval
+	ProgressInterval time.Duration
+	// Maximum response body size in bytes, default is unlimited
+	MaxResponseSize int64
+	// Record every attempt in HTTP Archive format, default is none
+	HAR *HARRecorder
+	// Receive every attempt as a curl command, default is none
+	CurlLog func(command string)
+	// Headers redacted in the curl commands
+	CurlLogRedact []string
+
+	// cache keys with a background revalidation in flight
+	revalidations sync.Map
+}
+
+// Request - a request with optional per request configuration, sent with Client.Do
+type Request struct {
+	Method  string
+	URL     string
+	Body    io.Reader
+	Headers map[string]string
+	// Returns a new body for each attempt, takes precedence over Body. Used for bodies that can be re-opened, e.g. files
+	GetBody func() (io.ReadCloser, error)
+	// Length of the body returned by GetBody, unknown when <= 0
+	ContentLength int64
+	// Override the client's AttemptTimeout for this request
+	AttemptTimeout time.Duration
+	// Override the client's Timeout for this request
+	Timeout time.Duration
+	// Override the client's Progress for this request
+	Progress ProgressFunc
+	// Override the client's MaxResponseSize for this request
+	MaxResponseSize int64
+	// long lived body, the http.Client timeout only bounds the wait for the response headers
+	stream bool
 }
 
 var _ client = (*Client)(nil)
@@ -0,0 +1,108 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrResponseTooLarge - the response body is larger than the maximum response size
+	ErrResponseTooLarge = errors.New("response too large")
+	// ErrInvalidResponseSize - the maximum response size is negative
+	ErrInvalidResponseSize = errors.New("invalid maximum response size")
+)
+
+// ResponseTooLargeError - the response body exceeded the maximum response size, matches ErrResponseTooLarge
+type ResponseTooLargeError struct {
+	// Maximum response size in bytes
+	Limit int64
+	// Content-Length of the response, -1 when the body was cut off while reading
+	ContentLength int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	if e.ContentLength >= 0 {
+		return fmt.Sprintf("%s: content length %d exceeds %d bytes", ErrResponseTooLarge, e.ContentLength, e.Limit)
+	}
+
+	return fmt.Sprintf("%s: body exceeds %d bytes", ErrResponseTooLarge, e.Limit)
+}
+
+func (e *ResponseTooLargeError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// WithMaxResponseSize - fail responses with a body larger than size bytes. The limit applies to the body as read, after decompression.
+// Uncompressed responses with a larger Content-Length are rejected before the body is read. Decompressed bodies, whose Content-Length
+// is the compressed size, and bodies of unknown length fail to read past the limit. 0 is unlimited
+func WithMaxResponseSize(size int64) FnOpts {
+	return func(o *Options) error {
+		if size < 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidResponseSize, size)
+		}
+		o.MaxResponseSize = size
+		return nil
+	}
+}
+
+// maxResponseSize - limit for the request, 0 when unlimited
+func (a *Client) maxResponseSize(r *Request) int64 {
+	if r.MaxResponseSize > 0 {
+		return r.MaxResponseSize
+	}
+
+	return a.MaxResponseSize
+}
+
+// rejectTooLarge - reject responses declaring a Content-Length over the limit, call after decompress as decoded bodies have no known length
+func (a *Client) rejectTooLarge(r *Request, resp *http.Response) error {
+	limit := a.maxResponseSize(r)
+	if limit <= 0 || resp.ContentLength <= limit {
+		return nil
+	}
+
+	return &ResponseTooLargeError{Limit: limit, ContentLength: resp.ContentLength}
+}
+
+// limitBody - fail reading the body past the limit
+func (a *Client) limitBody(r *Request, resp *http.Response) {
+	limit := a.maxResponseSize(r)
+	if limit <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit, limit: limit}
+}
+
+// limitedBody - returns ResponseTooLargeError once more than limit bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+	err       error
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	// read one byte past the limit to tell a body of exactly limit bytes from a larger one,
+	// remaining is below len(p) when truncating, so remaining+1 cannot overflow
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		l.err = &ResponseTooLargeError{Limit: l.limit, ContentLength: -1}
+		return n, l.err
+	}
+
+	l.remaining -= int64(n)
+	return n, err
+}
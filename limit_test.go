@@ -0,0 +1,145 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func TestClient_max_response_size(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	payload := bytes.Repeat([]byte("x"), 10<<10)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithMaxResponseSize(1024)))
+	})
+
+	err := group.
+		Test("content length over the limit should be rejected before reading", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+				_, _ = w.Write(payload)
+			})
+
+			_, err := client.Get(srv.URL, nil)
+			odize.AssertTrue(t, errors.Is(err, ErrResponseTooLarge))
+
+			var tooLarge *ResponseTooLargeError
+			odize.AssertTrue(t, errors.As(err, &tooLarge))
+			odize.AssertEqual(t, int64(1024), tooLarge.Limit)
+			odize.AssertEqual(t, int64(len(payload)), tooLarge.ContentLength)
+		}).
+		Test("streamed body over the limit should fail to read", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				flusher, ok := w.(http.Flusher)
+				odize.AssertTrue(t, ok)
+				for range 10 {
+					_, _ = w.Write(payload[:1024])
+					flusher.Flush()
+				}
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+
+			data, err := io.ReadAll(resp.Body)
+			odize.AssertTrue(t, errors.Is(err, ErrResponseTooLarge))
+			odize.AssertEqual(t, 1024, len(data))
+		}).
+		Test("body at the limit should be read", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(payload[:1024])
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 1024, len(readBody(t, resp)))
+		}).
+		Test("maximum int64 limit should read the whole body", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(payload)
+			})
+
+			resp, err := New(WithOpts(WithMaxResponseSize(math.MaxInt64))).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, len(payload), len(readBody(t, resp)))
+		}).
+		Test("request limit should override the client", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(payload)
+			})
+
+			resp, err := client.Do(context.Background(), &Request{Method: http.MethodGet, URL: srv.URL, MaxResponseSize: int64(len(payload))})
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, len(payload), len(readBody(t, resp)))
+		}).
+		Test("compressed body should be limited by its decompressed size", func(t *testing.T) {
+			compressed := encode(t, "gzip", payload)
+			odize.AssertTrue(t, len(compressed) < 1024)
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+				_, _ = w.Write(compressed)
+			})
+
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+
+			data, err := io.ReadAll(resp.Body)
+			odize.AssertTrue(t, errors.Is(err, ErrResponseTooLarge))
+			odize.AssertEqual(t, 1024, len(data))
+		}).
+		Test("compressed content length should only be rejected before reading when bodies are not decoded", func(t *testing.T) {
+			compressed := encode(t, "gzip", payload[:1024])
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+				_, _ = w.Write(compressed)
+			})
+
+			resp, err := New(WithOpts(WithMaxResponseSize(int64(len(compressed)-1)))).Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+
+			_, err = io.ReadAll(resp.Body)
+			odize.AssertTrue(t, errors.Is(err, ErrResponseTooLarge))
+
+			_, err = New(WithOpts(WithMaxResponseSize(int64(len(compressed)-1)), WithoutDecompression())).Get(srv.URL, nil)
+			var tooLarge *ResponseTooLargeError
+			odize.AssertTrue(t, errors.As(err, &tooLarge))
+			odize.AssertEqual(t, int64(len(compressed)), tooLarge.ContentLength)
+		}).
+		Test("too large responses should not be retried", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write(payload)
+			})
+
+			retryClient := New(WithOpts(WithMaxResponseSize(1024), WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+			_, err := retryClient.Get(srv.URL, nil)
+			odize.AssertTrue(t, errors.Is(err, ErrResponseTooLarge))
+			odize.AssertEqual(t, int32(1), count.Load())
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestWithMaxResponseSize_negative(t *testing.T) {
+	options := Options{}
+	err := WithMaxResponseSize(-1)(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrInvalidResponseSize))
+}
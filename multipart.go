@@ -0,0 +1,323 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrMultipartNotReplayable - a part read from a non seekable reader was already sent and cannot be sent again on retry,
+// or a reader that can only be rewound is still being read by another copy of the body
+var ErrMultipartNotReplayable = errors.New("multipart part cannot be sent again")
+
+// Multipart - multipart/form-data body builder. The body is streamed, files are never buffered in memory
+type Multipart struct {
+	boundary string
+	parts    []multipartPart
+	err      error
+}
+
+// multipartPart - a single form part
+type multipartPart struct {
+	name        string
+	filename    string
+	contentType string
+	// size of the content, -1 when unknown
+	size int64
+	// open the content for an attempt
+	open func() (io.ReadCloser, error)
+}
+
+// NewMultipart - returns an empty multipart/form-data body with a random boundary
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+// Field - add a form field
+func (m *Multipart) Field(name, value string) *Multipart {
+	m.parts = append(m.parts, multipartPart{
+		name: name,
+		size: int64(len(value)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(value)), nil
+		},
+	})
+
+	return m
+}
+
+// File - add a file part read from r. Seekable readers are rewound for each retry, other readers can only be sent once.
+// Readers implementing io.ReaderAt, such as *os.File and *bytes.Reader, can be read by several copies of the body at once.
+// The part content type is detected from the filename extension.
+func (m *Multipart) File(name, filename string, r io.Reader) *Multipart {
+	size := int64(-1)
+	open := onceReader(r)
+
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			m.err = err
+			return m
+		}
+
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = seeker.Seek(start, io.SeekStart)
+		}
+		if err != nil {
+			m.err = err
+			return m
+		}
+
+		size = end - start
+		open = rewindReader(r, seeker, start)
+		if readerAt, ok := r.(io.ReaderAt); ok {
+			open = func() (io.ReadCloser, error) {
+				return io.NopCloser(io.NewSectionReader(readerAt, start, size)), nil
+			}
+		}
+	}
+
+	m.parts = append(m.parts, multipartPart{
+		name:        name,
+		filename:    filename,
+		contentType: contentTypeOf(filename),
+		size:        size,
+		open:        open,
+	})
+
+	return m
+}
+
+// FilePath - add a file part read from path, the file is opened for each attempt
+func (m *Multipart) FilePath(name, path string) *Multipart {
+	info, err := os.Stat(path)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	if !info.Mode().IsRegular() {
+		m.err = fmt.Errorf("multipart file %s is not a regular file", path)
+		return m
+	}
+
+	m.parts = append(m.parts, multipartPart{
+		name:        name,
+		filename:    filepath.Base(path),
+		contentType: contentTypeOf(path),
+		size:        info.Size(),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(filepath.Clean(path))
+		},
+	})
+
+	return m
+}
+
+// ContentType - multipart/form-data content type including the boundary
+func (m *Multipart) ContentType() string {
+	return mime.FormatMediaType("multipart/form-data", map[string]string{"boundary": m.boundary})
+}
+
+// Request - build a request streaming the body, with Content-Type set and Content-Length computed when every part size is known.
+// Each attempt streams the parts again, so the request can be retried.
+//
+// Example:
+//
+//	form := fetch.NewMultipart().
+//		Field("description", "quarterly report").
+//		FilePath("report", "/tmp/report.csv")
+//
+//	req, err := form.Request(http.MethodPost, url)
+//	if err != nil {
+//		// Handle error
+//	}
+//	resp, err := client.Do(ctx, req)
+func (m *Multipart) Request(method, url string) (*Request, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	length, err := m.contentLength()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		Method:        method,
+		URL:           url,
+		Headers:       map[string]string{"Content-Type": m.ContentType()},
+		GetBody:       m.body,
+		ContentLength: length,
+	}, nil
+}
+
+// PostMultipart - send the multipart body with POST
+func (a *Client) PostMultipart(ctx context.Context, url string, form *Multipart, headers map[string]string) (*http.Response, error) {
+	req, err := form.Request(http.MethodPost, url)
+	if err != nil {
+		return &http.Response{}, err
+	}
+	req.Headers = mergeHeaders(headers, req.Headers)
+
+	return a.do(ctx, req)
+}
+
+// body - open the sources and stream the parts through a pipe. Each call opens its own sources,
+// so copies of the body may be read concurrently, and a part that cannot be sent again fails the call
+func (m *Multipart) body() (io.ReadCloser, error) {
+	contents := make([]io.ReadCloser, 0, len(m.parts))
+	for _, part := range m.parts {
+		content, err := part.open()
+		if err != nil {
+			closeContents(contents)
+			return nil, err
+		}
+		contents = append(contents, content)
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		defer closeContents(contents)
+		writer.CloseWithError(m.write(writer, contents))
+	}()
+
+	return reader, nil
+}
+
+// write - write every part followed by the closing boundary
+func (m *Multipart) write(w io.Writer, contents []io.ReadCloser) error {
+	form := multipart.NewWriter(w)
+	if err := form.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for i, part := range m.parts {
+		partWriter, err := form.CreatePart(part.header())
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(partWriter, contents[i]); err != nil {
+			return err
+		}
+	}
+
+	return form.Close()
+}
+
+// closeContents - close the opened part sources
+func closeContents(contents []io.ReadCloser) {
+	for _, content := range contents {
+		_ = content.Close()
+	}
+}
+
+// contentLength - size of the encoded body, -1 when a part size is unknown
+func (m *Multipart) contentLength() (int64, error) {
+	counter := &countingWriter{}
+	form := multipart.NewWriter(counter)
+	if err := form.SetBoundary(m.boundary); err != nil {
+		return 0, err
+	}
+
+	var size int64
+	for _, part := range m.parts {
+		if part.size < 0 {
+			return -1, nil
+		}
+		size += part.size
+
+		if _, err := form.CreatePart(part.header()); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := form.Close(); err != nil {
+		return 0, err
+	}
+
+	return counter.n + size, nil
+}
+
+// header - MIME header of the part
+func (p multipartPart) header() textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	if p.filename == "" {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(p.name)))
+		return header
+	}
+
+	header.Set("Content-Disposition", multipart.FileContentDisposition(p.name, p.filename))
+	header.Set("Content-Type", p.contentType)
+	return header
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// contentTypeOf - content type from the file extension, application/octet-stream when unknown
+func contentTypeOf(filename string) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// onceReader - opens the reader for the first attempt only
+func onceReader(r io.Reader) func() (io.ReadCloser, error) {
+	var used atomic.Bool
+	return func() (io.ReadCloser, error) {
+		if !used.CompareAndSwap(false, true) {
+			return nil, ErrMultipartNotReplayable
+		}
+		return io.NopCloser(r), nil
+	}
+}
+
+// rewindReader - rewinds the reader for each attempt, failing instead of waiting while another copy of the body reads it
+func rewindReader(r io.Reader, seeker io.Seeker, start int64) func() (io.ReadCloser, error) {
+	var inUse atomic.Bool
+	return func() (io.ReadCloser, error) {
+		if !inUse.CompareAndSwap(false, true) {
+			return nil, ErrMultipartNotReplayable
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			inUse.Store(false)
+			return nil, err
+		}
+		return &releaseReader{Reader: r, release: func() { inUse.Store(false) }}, nil
+	}
+}
+
+// releaseReader - calls release when closed
+type releaseReader struct {
+	io.Reader
+	release func()
+}
+
+func (r *releaseReader) Close() error {
+	r.release()
+	return nil
+}
+
+// countingWriter - counts the bytes written
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
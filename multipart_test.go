@@ -0,0 +1,190 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// multipartUpload - the parsed form received by the test server
+type multipartUpload struct {
+	contentLength int64
+	fields        map[string]string
+	files         map[string]string
+	filenames     map[string]string
+	contentTypes  map[string]string
+}
+
+// multipartServer - test server parsing multipart uploads, failing the first failures requests with 503
+func multipartServer(t *testing.T, failures int32) (string, *[]multipartUpload) {
+	var uploads []multipartUpload
+	srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+		reader, err := r.MultipartReader()
+		odize.AssertNoError(t, err)
+
+		upload := multipartUpload{
+			contentLength: r.ContentLength,
+			fields:        map[string]string{},
+			files:         map[string]string{},
+			filenames:     map[string]string{},
+			contentTypes:  map[string]string{},
+		}
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			odize.AssertNoError(t, err)
+
+			data, err := io.ReadAll(part)
+			odize.AssertNoError(t, err)
+			if part.FileName() == "" {
+				upload.fields[part.FormName()] = string(data)
+				continue
+			}
+			upload.files[part.FormName()] = string(data)
+			upload.filenames[part.FormName()] = part.FileName()
+			upload.contentTypes[part.FormName()] = part.Header.Get("Content-Type")
+		}
+		uploads = append(uploads, upload)
+
+		if count <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	return srv.URL, &uploads
+}
+
+func writeTempFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	odize.AssertNoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestMultipart(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	retryClient := New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+
+	err := group.
+		Test("should stream fields and files with a computed content length", func(t *testing.T) {
+			url, uploads := multipartServer(t, 0)
+			path := writeTempFile(t, "report.csv", "a,b\n1,2\n")
+
+			form := NewMultipart().
+				Field("description", `quarterly "report"`).
+				FilePath("report", path).
+				File("notes", "notes.bin", bytes.NewReader([]byte{1, 2, 3}))
+
+			req, err := form.Request(http.MethodPost, url)
+			odize.AssertNoError(t, err)
+			odize.AssertTrue(t, req.ContentLength > 0)
+
+			resp, err := New(nil).Do(context.Background(), req)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			odize.AssertEqual(t, 1, len(*uploads))
+			upload := (*uploads)[0]
+			odize.AssertEqual(t, req.ContentLength, upload.contentLength)
+			odize.AssertEqual(t, `quarterly "report"`, upload.fields["description"])
+			odize.AssertEqual(t, "a,b\n1,2\n", upload.files["report"])
+			odize.AssertEqual(t, "report.csv", upload.filenames["report"])
+			odize.AssertTrue(t, strings.HasPrefix(upload.contentTypes["report"], "text/csv"))
+			odize.AssertEqual(t, string([]byte{1, 2, 3}), upload.files["notes"])
+			odize.AssertEqual(t, "application/octet-stream", upload.contentTypes["notes"])
+		}).
+		Test("retries should re-open the file sources", func(t *testing.T) {
+			url, uploads := multipartServer(t, 1)
+			path := writeTempFile(t, "data.json", `{"id":1}`)
+
+			form := NewMultipart().
+				FilePath("data", path).
+				File("extra", "extra.txt", strings.NewReader("extra"))
+
+			resp, err := retryClient.PostMultipart(context.Background(), url, form, map[string]string{"X-Upload": "1"})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			odize.AssertEqual(t, 2, len(*uploads))
+			for _, upload := range *uploads {
+				odize.AssertEqual(t, `{"id":1}`, upload.files["data"])
+				odize.AssertEqual(t, "extra", upload.files["extra"])
+			}
+		}).
+		Test("unknown sizes should be sent chunked", func(t *testing.T) {
+			url, uploads := multipartServer(t, 0)
+
+			form := NewMultipart().File("stream", "stream.txt", io.LimitReader(strings.NewReader("streamed"), 100))
+			req, err := form.Request(http.MethodPost, url)
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, int64(-1), req.ContentLength)
+
+			resp, err := New(nil).Do(context.Background(), req)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+			odize.AssertEqual(t, int64(-1), (*uploads)[0].contentLength)
+			odize.AssertEqual(t, "streamed", (*uploads)[0].files["stream"])
+		}).
+		Test("non seekable readers should not be sent twice", func(t *testing.T) {
+			url, _ := multipartServer(t, 1)
+
+			form := NewMultipart().File("stream", "stream.txt", io.LimitReader(strings.NewReader("streamed"), 100))
+			_, err := retryClient.PostMultipart(context.Background(), url, form, nil)
+			odize.AssertTrue(t, errors.Is(err, ErrMultipartNotReplayable))
+		}).
+		Test("a signer and curl log reading the body should not block the attempt", func(t *testing.T) {
+			url, uploads := multipartServer(t, 0)
+			path := writeTempFile(t, "report.csv", "a,b\n1,2\n")
+
+			var commands []string
+			client := New(WithOpts(
+				WithSigner(signerFunc(func(req *http.Request) error {
+					body, err := req.GetBody()
+					if err != nil {
+						return err
+					}
+					defer body.Close()
+					data, err := io.ReadAll(body)
+					req.Header.Set("X-Body-Size", strconv.Itoa(len(data)))
+					return err
+				})),
+				WithCurlLog(func(command string) { commands = append(commands, command) }),
+			))
+
+			for range 20 {
+				form := NewMultipart().
+					Field("description", "report").
+					FilePath("report", path).
+					File("notes", "notes.txt", strings.NewReader("notes"))
+
+				resp, err := client.PostMultipart(context.Background(), url, form, nil)
+				odize.AssertNoError(t, err)
+				readBody(t, resp)
+			}
+
+			odize.AssertEqual(t, 20, len(*uploads))
+			odize.AssertEqual(t, 20, len(commands))
+			for _, upload := range *uploads {
+				odize.AssertEqual(t, "a,b\n1,2\n", upload.files["report"])
+				odize.AssertEqual(t, "notes", upload.files["notes"])
+			}
+		}).
+		Test("missing file should fail to build the request", func(t *testing.T) {
+			_, err := NewMultipart().FilePath("missing", filepath.Join(t.TempDir(), "missing.txt")).Request(http.MethodPost, "http://localhost")
+			odize.AssertTrue(t, errors.Is(err, os.ErrNotExist))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -15,6 +15,42 @@ type Options struct {
 	RetryStrategy *[]time.Duration
 	// Provide a custom retry strategy
 	HTTPClient *http.Client
+	// Sign every request attempt, e.g. with a SigV4Signer
+	Signer Signer
+	// TLS configuration for the default transport, ignored when HTTPClient is set
+	TLS *TLSOptions
+	// Tuning for the default transport, ignored when HTTPClient is set
+	Transport *TransportOptions
+	// Maximum duration of a single attempt. Replaces the default client's 15s timeout
+	AttemptTimeout time.Duration
+	// Maximum duration of a call, including retries and backoff waits
+	Timeout time.Duration
+	// Cache GET responses, e.g. NewMemoryCache or NewDiskCache
+	Cache CacheStore
+	// Store used for automatic conditional GET requests
+	ConditionalStore CacheStore
+	// Share in flight identical GET / HEAD requests
+	Coalescer *Coalescer
+	// Keep compressed response bodies as sent by the server, default is to decode gzip, deflate, br and zstd
+	DisableDecompression bool
+	// Maximum decompressed size of a response body in bytes, default is DefaultMaxDecompressedSize
+	MaxDecompressedSize int64
+	// Compress request bodies, default is none
+	RequestCompression *RequestCompression
+	// Report upload and download progress, default is none
+	Progress ProgressFunc
+	// Minimum interval between progress reports, default is DefaultProgressInterval
+	ProgressInterval time.Duration
+	// Maximum response body size in bytes, default is unlimited
+	MaxResponseSize int64
+	// Record every attempt in HTTP Archive format, default is none
+	HAR *HARRecorder
+	// Inject faults into requests for chaos testing, default is none
+	FaultInjector *FaultInjector
+	// Receive every attempt as a curl command, default is none
+	CurlLog func(command string)
+	// Headers redacted in the curl commands
+	CurlLogRedact []string
 }
 
 type FnOpts = func(o *Options) error
@@ -69,6 +105,30 @@ func WithHTTPClient(client *http.Client) FnOpts {
 	}
 }
 
+// WithSigner - sign every request attempt with the provided signer
+func WithSigner(signer Signer) FnOpts {
+	return func(o *Options) error {
+		o.Signer = signer
+		return nil
+	}
+}
+
+// WithAttemptTimeout - maximum duration of a single attempt, timed out attempts are retried
+func WithAttemptTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		o.AttemptTimeout = timeout
+		return nil
+	}
+}
+
+// WithTimeout - maximum duration of a call, including retries and backoff waits
+func WithTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		o.Timeout = timeout
+		return nil
+	}
+}
+
 // setDefaultRetryStrategy - sets the retry attempts
 func setDefaultRetryStrategy() []time.Duration {
 	return []time.Duration{
@@ -79,28 +139,33 @@ func setDefaultRetryStrategy() []time.Duration {
 	}
 }
 
-// setDefaultClient - returns the default http client
-func setDefaultClient() *http.Client {
+// setDefaultTransport - returns the default http transport
+func setDefaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 15 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// setDefaultClient - returns the default http client using the provided transport
+func setDefaultClient(transport *http.Transport) *http.Client {
 	return &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   15 * time.Second,
-				KeepAlive: 15 * time.Second,
-			}).DialContext,
-			ForceAttemptHTTP2:     true,
-			MaxIdleConns:          10,
-			IdleConnTimeout:       30 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
-		Timeout: time.Second * 15,
+		Transport: transport,
+		Timeout:   time.Second * 15,
 	}
 }
 
 func setDefaultFetch() *Client {
 	return &Client{
 		RetryStrategy: setDefaultRetryStrategy(),
-		Client:        setDefaultClient(),
+		Client:        setDefaultClient(setDefaultTransport()),
 	}
 }
@@ -0,0 +1,358 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrPageItems - the items of a page could not be decoded
+var ErrPageItems = errors.New("invalid page items")
+
+// PageStrategy - finds the next page of a paginated API
+type PageStrategy interface {
+	// First - the URL of the first page, e.g. adding the starting offset
+	First(u *url.URL) *url.URL
+	// Next - the URL of the page after current, nil when there are no more pages
+	Next(current *url.URL, resp *http.Response, body []byte, items int) (*url.URL, error)
+}
+
+// Pagination - configuration for Paginate
+type Pagination struct {
+	// Strategy finding the next page, default is LinkHeaderPages
+	Strategy PageStrategy
+	// Dot separated path to the items array in the page body, e.g. "data.items". Default is the body itself
+	Items string
+	// Maximum number of pages requested, default is unlimited
+	MaxPages int
+	// Headers added to each page request
+	Headers map[string]string
+}
+
+// Paginate - request each page of a paginated API with GET and yield the items one at a time.
+// Iteration stops after yielding an error, when the strategy finds no next page, after MaxPages pages or when the context is done.
+//
+// Example:
+//
+//	pages := fetch.Pagination{Strategy: fetch.CursorPages{Param: "cursor", Field: "meta.next"}, Items: "data"}
+//	for user, err := range fetch.Paginate[User](ctx, client, url, pages) {
+//		if err != nil {
+//			// Handle error
+//			break
+//		}
+//		fmt.Println(user)
+//	}
+func Paginate[T any](ctx context.Context, c *Client, rawURL string, pagination Pagination) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		strategy := pagination.Strategy
+		if strategy == nil {
+			strategy = LinkHeaderPages{}
+		}
+
+		current, err := url.Parse(rawURL)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		current = strategy.First(current)
+
+		for page := 0; pagination.MaxPages <= 0 || page < pagination.MaxPages; page++ {
+			if ctx.Err() != nil {
+				yield(zero, context.Cause(ctx))
+				return
+			}
+
+			resp, body, err := fetchPage(ctx, c, current.String(), pagination.Headers)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			items, err := pageItems[T](body, pagination.Items)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			next, err := strategy.Next(current, resp, body, len(items))
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if next == nil || next.String() == current.String() {
+				return
+			}
+			current = next
+		}
+	}
+}
+
+// fetchPage - request a page and read its body
+func fetchPage(ctx context.Context, c *Client, rawURL string, headers map[string]string) (*http.Response, []byte, error) {
+	resp, err := c.GetCtx(ctx, rawURL, headers)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			discard(resp)
+		}
+		return resp, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// pageItems - decode the items array found at path in the body
+func pageItems[T any](body []byte, path string) ([]T, error) {
+	raw, err := jsonPath(body, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return nil, nil
+	}
+
+	var items []T
+	if err = json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPageItems, err)
+	}
+
+	return items, nil
+}
+
+// jsonPath - raw JSON value at the dot separated path, empty when a key is missing
+func jsonPath(body []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(body)
+	if path == "" {
+		return raw, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("%w: %s is not an object: %w", ErrPageItems, path, err)
+		}
+
+		var ok bool
+		if raw, ok = object[key]; !ok {
+			return nil, nil
+		}
+	}
+
+	return raw, nil
+}
+
+// LinkHeaderPages - follow the RFC 8288 Link header with rel="next"
+type LinkHeaderPages struct{}
+
+// First - the URL as provided
+func (LinkHeaderPages) First(u *url.URL) *url.URL {
+	return u
+}
+
+// Next - the next link, resolved against the current URL
+func (LinkHeaderPages) Next(current *url.URL, resp *http.Response, _ []byte, _ int) (*url.URL, error) {
+	for _, link := range parseLinks(resp.Header.Values("Link")) {
+		if !link.hasRel("next") {
+			continue
+		}
+
+		next, err := current.Parse(link.target)
+		if err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+
+	return nil, nil
+}
+
+// CursorPages - read the next cursor from the page body and send it as a query parameter
+type CursorPages struct {
+	// Query parameter carrying the cursor, e.g. "cursor"
+	Param string
+	// Dot separated path to the next cursor in the page body, e.g. "meta.next_cursor". A missing, null or empty cursor ends pagination
+	Field string
+}
+
+// First - the URL as provided
+func (CursorPages) First(u *url.URL) *url.URL {
+	return u
+}
+
+// Next - the current URL with the cursor parameter replaced
+func (p CursorPages) Next(current *url.URL, _ *http.Response, body []byte, _ int) (*url.URL, error) {
+	raw, err := jsonPath(body, p.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor any
+	if len(raw) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err = decoder.Decode(&cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	if cursor == nil || cursor == "" {
+		return nil, nil
+	}
+
+	return withQuery(current, map[string]string{p.Param: fmt.Sprint(cursor)}), nil
+}
+
+// OffsetPages - page or offset query parameter, incremented for each page.
+// Pagination ends on an empty page, or a page with fewer than Limit items.
+type OffsetPages struct {
+	// Query parameter incremented for each page, e.g. "page" or "offset"
+	Param string
+	// Value of Param for the first page
+	Start int
+	// Increment of Param per page, e.g. 1 for page numbers. Default is the number of items on the page, for offsets
+	Step int
+	// Page size, used to detect the last page and sent as LimitParam when set
+	Limit int
+	// Query parameter carrying the page size, e.g. "limit" or "per_page"
+	LimitParam string
+}
+
+// First - the URL with the start and limit parameters set
+func (p OffsetPages) First(u *url.URL) *url.URL {
+	query := map[string]string{p.Param: strconv.Itoa(p.Start)}
+	if p.Limit > 0 && p.LimitParam != "" {
+		query[p.LimitParam] = strconv.Itoa(p.Limit)
+	}
+
+	return withQuery(u, query)
+}
+
+// Next - the current URL with Param incremented
+func (p OffsetPages) Next(current *url.URL, _ *http.Response, _ []byte, items int) (*url.URL, error) {
+	if items == 0 || (p.Limit > 0 && items < p.Limit) {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(current.Query().Get(p.Param))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s query parameter: %w", p.Param, err)
+	}
+
+	step := p.Step
+	if step <= 0 {
+		step = items
+	}
+
+	return withQuery(current, map[string]string{p.Param: strconv.Itoa(value + step)}), nil
+}
+
+// withQuery - copy of u with the query parameters replaced
+func withQuery(u *url.URL, params map[string]string) *url.URL {
+	next := *u
+	query := next.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	next.RawQuery = query.Encode()
+
+	return &next
+}
+
+// link - a single RFC 8288 link
+type link struct {
+	target string
+	rel    []string
+}
+
+func (l link) hasRel(rel string) bool {
+	for _, r := range l.rel {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseLinks - parse Link header values, e.g. `<https://api.example.com/items?page=2>; rel="next"`
+func parseLinks(values []string) []link {
+	var links []link
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+
+			current := link{target: value[start+1 : end]}
+			value = value[end+1:]
+
+			params, rest := splitLinkParams(value)
+			value = rest
+			for _, param := range params {
+				key, val, _ := strings.Cut(param, "=")
+				if strings.EqualFold(strings.TrimSpace(key), "rel") {
+					current.rel = strings.Fields(strings.Trim(strings.TrimSpace(val), `"`))
+				}
+			}
+
+			links = append(links, current)
+		}
+	}
+
+	return links
+}
+
+// splitLinkParams - the ; separated parameters of a link up to the next link, honouring quoted strings
+func splitLinkParams(value string) ([]string, string) {
+	var params []string
+	var builder strings.Builder
+	quoted := false
+
+	for i := 0; i < len(value); i++ {
+		char := value[i]
+		switch {
+		case char == '"':
+			quoted = !quoted
+			builder.WriteByte(char)
+		case char == ';' && !quoted:
+			if param := strings.TrimSpace(builder.String()); param != "" {
+				params = append(params, param)
+			}
+			builder.Reset()
+		case char == ',' && !quoted:
+			if param := strings.TrimSpace(builder.String()); param != "" {
+				params = append(params, param)
+			}
+			return params, value[i+1:]
+		default:
+			builder.WriteByte(char)
+		}
+	}
+
+	if param := strings.TrimSpace(builder.String()); param != "" {
+		params = append(params, param)
+	}
+
+	return params, ""
+}
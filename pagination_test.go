@@ -0,0 +1,177 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// collectPages - collect every item and the last error
+func collectPages[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+	for item, err := range seq {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func TestParseLinks(t *testing.T) {
+	links := parseLinks([]string{
+		`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=9>; rel="last"`,
+		`</items?page=1>; title="a; b, c"; rel="prev first"`,
+	})
+
+	odize.AssertEqual(t, 3, len(links))
+	odize.AssertEqual(t, "https://api.example.com/items?page=2", links[0].target)
+	odize.AssertTrue(t, links[0].hasRel("next"))
+	odize.AssertTrue(t, links[1].hasRel("last"))
+	odize.AssertEqual(t, "/items?page=1", links[2].target)
+	odize.AssertTrue(t, links[2].hasRel("first"))
+	odize.AssertFalse(t, links[2].hasRel("next"))
+}
+
+func TestPaginate(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(nil)
+	})
+
+	err := group.
+		Test("link header should be followed until there is no next page", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				if page < 3 {
+					w.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next"`, page+1))
+				}
+				_, _ = fmt.Fprintf(w, `[%d,%d]`, page*10+1, page*10+2)
+			})
+
+			items, err := collectPages(Paginate[int](context.Background(), client, srv.URL+"/items?page=1", Pagination{}))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, []int{11, 12, 21, 22, 31, 32}, items)
+			odize.AssertEqual(t, int32(3), count.Load())
+		}).
+		Test("cursor should be read from the body", func(t *testing.T) {
+			pages := map[string]string{
+				"":  `{"data":[{"id":1}],"meta":{"next":"b"}}`,
+				"b": `{"data":[{"id":2}],"meta":{"next":"c"}}`,
+				"c": `{"data":[{"id":3}],"meta":{"next":null}}`,
+			}
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				_, _ = w.Write([]byte(pages[r.URL.Query().Get("cursor")]))
+			})
+
+			pagination := Pagination{Strategy: CursorPages{Param: "cursor", Field: "meta.next"}, Items: "data"}
+			items, err := collectPages(Paginate[decodeRecord](context.Background(), client, srv.URL, pagination))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, []decodeRecord{{ID: 1}, {ID: 2}, {ID: 3}}, items)
+		}).
+		Test("page numbers should stop on a short page", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				odize.AssertEqual(t, "2", r.URL.Query().Get("per_page"))
+				switch r.URL.Query().Get("page") {
+				case "1":
+					_, _ = w.Write([]byte(`[1,2]`))
+				case "2":
+					_, _ = w.Write([]byte(`[3]`))
+				default:
+					t.Fatal("unexpected page")
+				}
+			})
+
+			pagination := Pagination{Strategy: OffsetPages{Param: "page", Start: 1, Step: 1, Limit: 2, LimitParam: "per_page"}}
+			items, err := collectPages(Paginate[int](context.Background(), client, srv.URL, pagination))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, []int{1, 2, 3}, items)
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("offsets should advance by the items on the page and stop on an empty page", func(t *testing.T) {
+			var offsets []string
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				offset := r.URL.Query().Get("offset")
+				offsets = append(offsets, offset)
+				if offset == "5" {
+					_, _ = w.Write([]byte(`{"items":[]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"items":[1,1,1,1,1]}`))
+			})
+
+			pagination := Pagination{Strategy: OffsetPages{Param: "offset"}, Items: "items"}
+			items, err := collectPages(Paginate[int](context.Background(), client, srv.URL, pagination))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 5, len(items))
+			odize.AssertEqual(t, []string{"0", "5"}, offsets)
+		}).
+		Test("max pages should limit the requests", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, n int32) {
+				w.Header().Set("Link", fmt.Sprintf(`<?page=%d>; rel="next"`, n+1))
+				_, _ = w.Write([]byte(`[1]`))
+			})
+
+			items, err := collectPages(Paginate[int](context.Background(), client, srv.URL, Pagination{MaxPages: 2}))
+			odize.AssertNoError(t, err)
+			odize.AssertEqual(t, 2, len(items))
+			odize.AssertEqual(t, int32(2), count.Load())
+		}).
+		Test("failed page should yield the api error", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, n int32) {
+				if n == 2 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Link", `<?page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[1]`))
+			})
+
+			items, err := collectPages(Paginate[int](context.Background(), client, srv.URL, Pagination{}))
+			var apiErr *APIError
+			odize.AssertTrue(t, errors.As(err, &apiErr))
+			odize.AssertEqual(t, []int{1}, items)
+		}).
+		Test("invalid items should yield ErrPageItems", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte(`{"items":"nope"}`))
+			})
+
+			_, err := collectPages(Paginate[int](context.Background(), client, srv.URL, Pagination{Items: "items"}))
+			odize.AssertTrue(t, errors.Is(err, ErrPageItems))
+		}).
+		Test("cancelled context should stop before the next page", func(t *testing.T) {
+			srv, count := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, n int32) {
+				w.Header().Set("Link", fmt.Sprintf(`<?page=%d>; rel="next"`, n+1))
+				data, _ := json.Marshal([]int32{n})
+				_, _ = w.Write(data)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var got error
+			for _, err := range Paginate[int](ctx, client, srv.URL, Pagination{}) {
+				if err != nil {
+					got = err
+					break
+				}
+				cancel()
+			}
+			odize.AssertTrue(t, errors.Is(got, context.Canceled))
+			odize.AssertEqual(t, int32(1), count.Load())
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,184 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval - minimum interval between progress reports when none is provided
+const DefaultProgressInterval = 200 * time.Millisecond
+
+// ProgressDirection - direction of the reported transfer
+type ProgressDirection string
+
+const (
+	// ProgressUpload - request body sent to the server
+	ProgressUpload ProgressDirection = "upload"
+	// ProgressDownload - response body read from the server
+	ProgressDownload ProgressDirection = "download"
+)
+
+// Progress - transfer progress of a request or response body
+type Progress struct {
+	Direction ProgressDirection
+	// Attempt number, starting at 1. Counters restart for each retry attempt
+	Attempt int
+	// Bytes transferred in this attempt
+	Transferred int64
+	// Size of the body, -1 when unknown
+	Total int64
+	// Average bytes per second since the start of the attempt
+	Rate float64
+	// Estimated time remaining, -1 when unknown
+	ETA time.Duration
+	// The whole body was transferred
+	Done bool
+}
+
+// ProgressFunc - receives progress reports, may be called from the transport's goroutines
+type ProgressFunc func(p Progress)
+
+// WithProgress - report upload and download progress for every request, at most once per interval plus a final report once a body is complete.
+// Uses DefaultProgressInterval when interval <= 0.
+func WithProgress(fn ProgressFunc, interval time.Duration) FnOpts {
+	return func(o *Options) error {
+		o.Progress = fn
+		o.ProgressInterval = interval
+		return nil
+	}
+}
+
+// progressTracker - wraps the bodies of a single attempt
+type progressTracker struct {
+	fn       ProgressFunc
+	interval time.Duration
+	attempt  int
+}
+
+// progressOf - tracker for the attempt, nil when progress is not reported
+func (a *Client) progressOf(r *Request, attempt int) *progressTracker {
+	fn := a.Progress
+	if r.Progress != nil {
+		fn = r.Progress
+	}
+
+	if fn == nil {
+		return nil
+	}
+
+	interval := a.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	return &progressTracker{fn: fn, interval: interval, attempt: attempt}
+}
+
+// upload - report progress reading the request body
+func (p *progressTracker) upload(req *http.Request) {
+	if p == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := req.ContentLength
+	if total <= 0 {
+		total = -1
+	}
+
+	req.Body = p.wrap(req.Body, ProgressUpload, total)
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return p.wrap(body, ProgressUpload, total), nil
+		}
+	}
+}
+
+// download - report progress reading the response body
+func (p *progressTracker) download(resp *http.Response) {
+	if p == nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	resp.Body = p.wrap(resp.Body, ProgressDownload, resp.ContentLength)
+}
+
+func (p *progressTracker) wrap(body io.ReadCloser, direction ProgressDirection, total int64) io.ReadCloser {
+	return &progressReader{
+		ReadCloser: body,
+		tracker:    p,
+		direction:  direction,
+		total:      total,
+	}
+}
+
+// progressReader - counts the bytes read and reports progress
+type progressReader struct {
+	io.ReadCloser
+	tracker     *progressTracker
+	direction   ProgressDirection
+	total       int64
+	mu          sync.Mutex
+	transferred int64
+	start       time.Time
+	reported    time.Time
+	done        bool
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+
+	if progress, ok := p.advance(int64(n), errors.Is(err, io.EOF)); ok {
+		p.tracker.fn(progress)
+	}
+
+	return n, err
+}
+
+// advance - count the bytes read, returns the progress to report when the interval elapsed or the body is complete
+func (p *progressReader) advance(n int64, eof bool) (Progress, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	p.transferred += n
+	eof = eof || (p.total > 0 && p.transferred >= p.total)
+
+	if p.done || (!eof && now.Sub(p.reported) < p.tracker.interval) {
+		return Progress{}, false
+	}
+
+	p.done = eof
+	p.reported = now
+
+	progress := Progress{
+		Direction:   p.direction,
+		Attempt:     p.tracker.attempt,
+		Transferred: p.transferred,
+		Total:       p.total,
+		ETA:         -1,
+		Done:        p.done,
+	}
+
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		progress.Rate = float64(p.transferred) / elapsed
+	}
+
+	switch {
+	case p.done:
+		progress.ETA = 0
+	case p.total >= 0 && progress.Rate > 0:
+		progress.ETA = time.Duration(float64(p.total-p.transferred) / progress.Rate * float64(time.Second))
+	}
+
+	return progress, true
+}
@@ -0,0 +1,188 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// progressRecorder - collects progress reports
+type progressRecorder struct {
+	mu      sync.Mutex
+	reports []Progress
+}
+
+func (p *progressRecorder) record(progress Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.reports = append(p.reports, progress)
+}
+
+func (p *progressRecorder) of(direction ProgressDirection) []Progress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var reports []Progress
+	for _, report := range p.reports {
+		if report.Direction == direction {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+func TestClient_progress(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	payload := bytes.Repeat([]byte("x"), 64<<10)
+
+	var recorder *progressRecorder
+
+	group.BeforeEach(func() {
+		recorder = &progressRecorder{}
+	})
+
+	err := group.
+		Test("download should report the final progress", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+				_, _ = w.Write(payload)
+			})
+
+			client := New(WithOpts(WithProgress(recorder.record, time.Nanosecond)))
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			reports := recorder.of(ProgressDownload)
+			odize.AssertTrue(t, len(reports) > 0)
+			last := reports[len(reports)-1]
+			odize.AssertTrue(t, last.Done)
+			odize.AssertEqual(t, int64(len(payload)), last.Transferred)
+			odize.AssertEqual(t, int64(len(payload)), last.Total)
+			odize.AssertEqual(t, time.Duration(0), last.ETA)
+			odize.AssertEqual(t, 1, last.Attempt)
+		}).
+		Test("long interval should only report completion", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(payload)
+			})
+
+			client := New(WithOpts(WithProgress(recorder.record, time.Hour)))
+			resp, err := client.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+
+			buf := make([]byte, 1024)
+			for {
+				if _, err = resp.Body.Read(buf); err != nil {
+					break
+				}
+			}
+			odize.AssertNoError(t, resp.Body.Close())
+
+			reports := recorder.of(ProgressDownload)
+			// the first read reports as nothing was reported yet
+			odize.AssertEqual(t, 2, len(reports))
+			odize.AssertFalse(t, reports[0].Done)
+			odize.AssertTrue(t, reports[1].Done)
+		}).
+		Test("upload should report the request body", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(_ http.ResponseWriter, r *http.Request, _ int32) {
+				_, _ = io.Copy(io.Discard, r.Body)
+			})
+
+			client := New(WithOpts(WithProgress(recorder.record, 0)))
+			resp, err := client.Post(srv.URL, bytes.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			reports := recorder.of(ProgressUpload)
+			odize.AssertTrue(t, len(reports) > 0)
+			last := reports[len(reports)-1]
+			odize.AssertTrue(t, last.Done)
+			odize.AssertEqual(t, int64(len(payload)), last.Transferred)
+			odize.AssertEqual(t, int64(len(payload)), last.Total)
+		}).
+		Test("retries should restart the counters", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+
+			client := New(WithOpts(
+				WithProgress(recorder.record, 0),
+				WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond}),
+			))
+			resp, err := client.Put(srv.URL, strings.NewReader(string(payload)), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			done := map[int]int64{}
+			for _, report := range recorder.of(ProgressUpload) {
+				if report.Done {
+					done[report.Attempt] = report.Transferred
+				}
+			}
+			odize.AssertEqual(t, map[int]int64{1: int64(len(payload)), 2: int64(len(payload))}, done)
+		}).
+		Test("a signer reading the body should not report an upload", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				if count == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+
+			client := New(WithOpts(
+				WithProgress(recorder.record, 0),
+				WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond}),
+				WithSigner(signerFunc(func(req *http.Request) error {
+					body, err := req.GetBody()
+					if err != nil {
+						return err
+					}
+					defer body.Close()
+					_, err = io.Copy(io.Discard, body)
+					return err
+				})),
+			))
+			resp, err := client.Put(srv.URL, bytes.NewReader(payload), nil)
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			done := map[int]int{}
+			for _, report := range recorder.of(ProgressUpload) {
+				if report.Done {
+					done[report.Attempt]++
+				}
+			}
+			odize.AssertEqual(t, map[int]int{1: 1, 2: 1}, done)
+		}).
+		Test("request progress should override the client", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write(payload)
+			})
+
+			clientRecorder := &progressRecorder{}
+			client := New(WithOpts(WithProgress(clientRecorder.record, 0)))
+			resp, err := client.Do(context.Background(), &Request{Method: http.MethodGet, URL: srv.URL, Progress: recorder.record})
+			odize.AssertNoError(t, err)
+			readBody(t, resp)
+
+			odize.AssertTrue(t, len(recorder.of(ProgressDownload)) > 0)
+			odize.AssertEqual(t, 0, len(clientRecorder.of(ProgressDownload)))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,531 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm        = "AWS4-HMAC-SHA256"
+	sigV4ChunkAlgorithm   = "AWS4-HMAC-SHA256-PAYLOAD"
+	sigV4TimeFormat       = "20060102T150405Z"
+	sigV4DateFormat       = "20060102"
+	sigV4Terminator       = "aws4_request"
+	sigV4EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// UnsignedPayload - payload hash used when the body is not signed
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// StreamingPayload - payload hash used when the body is signed chunk by chunk (aws-chunked)
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+	headerAmzDate          = "X-Amz-Date"
+	headerAmzContentSHA256 = "X-Amz-Content-Sha256"
+	headerAmzSecurityToken = "X-Amz-Security-Token"
+	headerAmzDecodedLength = "X-Amz-Decoded-Content-Length"
+
+	// defaultChunkSize - chunk size used for streaming payloads, 64KB
+	defaultChunkSize = 64 * 1024
+)
+
+var (
+	ErrMissingCredentials     = errors.New("sigv4: missing credentials")
+	ErrStreamingContentLength = errors.New("sigv4: streaming payload requires a known content length")
+)
+
+// sigV4IgnoredHeaders - headers that may be modified in transit and are never signed
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":     true,
+	"user-agent":        true,
+	"x-amzn-trace-id":   true,
+	"expect":            true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
+// Credentials - AWS credentials used to sign requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// Optional, set when using temporary credentials
+	SessionToken string
+}
+
+// SigV4Signer - signs requests with AWS Signature Version 4.
+// Works with AWS APIs and S3-compatible storage such as MinIO.
+//
+// Example:
+//
+//	signer := &fetch.SigV4Signer{
+//		Credentials: fetch.Credentials{AccessKeyID: "id", SecretAccessKey: "secret"},
+//		Region:      "us-east-1",
+//		Service:     "s3",
+//	}
+//
+//	client := fetch.New(fetch.WithOpts(fetch.WithSigner(signer)))
+type SigV4Signer struct {
+	// Static credentials, ignored when CredentialsProvider is set
+	Credentials Credentials
+	// Optional, resolve credentials on every signature, e.g. to support rotation
+	CredentialsProvider func() (Credentials, error)
+	// AWS region, e.g. us-east-1
+	Region string
+	// AWS service name, e.g. s3, execute-api
+	Service string
+	// Send UNSIGNED-PAYLOAD instead of hashing the body
+	UnsignedPayload bool
+	// Sign the body chunk by chunk using aws-chunked encoding, the body is never buffered.
+	// Requires a known content length.
+	StreamingPayload bool
+	// Chunk size used for streaming payloads, default is 64KB
+	ChunkSize int
+	// Do not escape the URI path a second time. Always true for the s3 service
+	DisableURIPathEscaping bool
+	// Clock override, default is time.Now
+	Now func() time.Time
+}
+
+var _ Signer = (*SigV4Signer)(nil)
+
+// Sign - add the SigV4 Authorization header to the request.
+//
+// The payload hash is resolved in the following order:
+//   - an X-Amz-Content-Sha256 header already set on the request
+//   - UNSIGNED-PAYLOAD when UnsignedPayload is set
+//   - aws-chunked signing when StreamingPayload is set
+//   - the SHA-256 of the body, buffering it when the request can not replay the body
+func (s *SigV4Signer) Sign(req *http.Request) error {
+	creds, err := s.credentials()
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	req.Header.Set(headerAmzDate, now.Format(sigV4TimeFormat))
+	if creds.SessionToken != "" {
+		req.Header.Set(headerAmzSecurityToken, creds.SessionToken)
+	}
+
+	payloadHash, err := s.payloadHash(req)
+	if err != nil {
+		return err
+	}
+
+	if payloadHash == StreamingPayload {
+		if err = prepareStreamingRequest(req, s.chunkSize()); err != nil {
+			return err
+		}
+	}
+
+	if s.isS3() || req.Header.Get(headerAmzContentSHA256) != "" || payloadHash == UnsignedPayload || payloadHash == StreamingPayload {
+		req.Header.Set(headerAmzContentSHA256, payloadHash)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	key := s.signingKey(creds.SecretAccessKey, now)
+	signature := hmacHex(key, stringToSign(now, scope, canonicalRequest))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+
+	if payloadHash == StreamingPayload {
+		req.Body = io.NopCloser(newChunkSigner(req.Body, s.chunkSize(), key, now, scope, signature))
+		req.GetBody = nil
+	}
+
+	return nil
+}
+
+// Presign - returns a presigned URL for the request, valid for the provided duration.
+// The payload is always UNSIGNED-PAYLOAD, any headers on the request must be sent by the holder of the URL.
+func (s *SigV4Signer) Presign(req *http.Request, expires time.Duration) (string, error) {
+	creds, err := s.credentials()
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+	scope := s.scope(now)
+
+	presigned := *req.URL
+	query := presigned.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(&presigned),
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		UnsignedPayload,
+	}, "\n")
+
+	key := s.signingKey(creds.SecretAccessKey, now)
+	query.Set("X-Amz-Signature", hmacHex(key, stringToSign(now, scope, canonicalRequest)))
+
+	presigned.RawQuery = canonicalQuery(query)
+	return presigned.String(), nil
+}
+
+// credentials - resolve credentials to sign with
+func (s *SigV4Signer) credentials() (Credentials, error) {
+	creds := s.Credentials
+	if s.CredentialsProvider != nil {
+		var err error
+		if creds, err = s.CredentialsProvider(); err != nil {
+			return creds, err
+		}
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, ErrMissingCredentials
+	}
+
+	return creds, nil
+}
+
+// payloadHash - resolve the payload hash for the request
+func (s *SigV4Signer) payloadHash(req *http.Request) (string, error) {
+	if hash := req.Header.Get(headerAmzContentSHA256); hash != "" {
+		return hash, nil
+	}
+
+	if s.UnsignedPayload {
+		return UnsignedPayload, nil
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return sigV4EmptyPayloadHash, nil
+	}
+
+	if s.StreamingPayload {
+		return StreamingPayload, nil
+	}
+
+	if req.GetBody == nil {
+		if err := bufferBody(req); err != nil {
+			return "", err
+		}
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (s *SigV4Signer) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+
+	return time.Now().UTC()
+}
+
+func (s *SigV4Signer) chunkSize() int {
+	if s.ChunkSize > 0 {
+		return s.ChunkSize
+	}
+
+	return defaultChunkSize
+}
+
+func (s *SigV4Signer) isS3() bool {
+	return s.Service == "s3"
+}
+
+// scope - credential scope, date/region/service/aws4_request
+func (s *SigV4Signer) scope(now time.Time) string {
+	return strings.Join([]string{now.Format(sigV4DateFormat), s.Region, s.Service, sigV4Terminator}, "/")
+}
+
+// signingKey - derive the signing key for the day, region and service
+func (s *SigV4Signer) signingKey(secret string, now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	return hmacSHA256(key, sigV4Terminator)
+}
+
+// canonicalURI - S3 escapes the path once, every other service escapes the escaped path again
+func (s *SigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+
+	if path == "" {
+		return "/"
+	}
+
+	if s.DisableURIPathEscaping || s.isS3() {
+		return path
+	}
+
+	return uriEncode(path, false)
+}
+
+// stringToSign - build the string to sign for a canonical request
+func stringToSign(now time.Time, scope string, canonicalRequest string) string {
+	return strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+}
+
+// canonicalHeaders - returns the signed header list and the canonical header block
+func canonicalHeaders(req *http.Request) (string, string) {
+	values := map[string][]string{}
+	for key, value := range req.Header {
+		name := strings.ToLower(key)
+		if sigV4IgnoredHeaders[name] {
+			continue
+		}
+		values[name] = append(values[name], value...)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values["host"] = []string{host}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		trimmed := make([]string, 0, len(values[name]))
+		for _, value := range values[name] {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		builder.WriteString(name)
+		builder.WriteString(":")
+		builder.WriteString(strings.Join(trimmed, ","))
+		builder.WriteString("\n")
+	}
+
+	return strings.Join(names, ";"), builder.String()
+}
+
+// canonicalQuery - sorted and URI encoded query string
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		encodedKey := uriEncode(key, true)
+		for _, value := range values {
+			pairs = append(pairs, encodedKey+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode - encode every byte except the unreserved characters, optionally leaving slashes as is
+func uriEncode(value string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+
+	return builder.String()
+}
+
+// bufferBody - read a non replayable body into memory so it can be hashed and sent
+func bufferBody(req *http.Request) error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	_ = req.Body.Close()
+
+	req.ContentLength = int64(len(data))
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return nil
+}
+
+// prepareStreamingRequest - set the aws-chunked headers and the encoded content length
+func prepareStreamingRequest(req *http.Request, chunkSize int) error {
+	decodedLength := req.ContentLength
+	if value := req.Header.Get(headerAmzDecodedLength); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		decodedLength = parsed
+	}
+
+	if decodedLength <= 0 {
+		return ErrStreamingContentLength
+	}
+
+	encodedLength := streamingContentLength(decodedLength, chunkSize)
+
+	contentEncoding := "aws-chunked"
+	if existing := req.Header.Get("Content-Encoding"); existing != "" && existing != contentEncoding {
+		contentEncoding += "," + existing
+	}
+
+	req.Header.Set("Content-Encoding", contentEncoding)
+	req.Header.Set(headerAmzDecodedLength, strconv.FormatInt(decodedLength, 10))
+	req.Header.Set("Content-Length", strconv.FormatInt(encodedLength, 10))
+	req.ContentLength = encodedLength
+
+	return nil
+}
+
+// streamingContentLength - length of the aws-chunked encoded body
+func streamingContentLength(decodedLength int64, chunkSize int) int64 {
+	size := int64(chunkSize)
+	full := decodedLength / size
+	remainder := decodedLength % size
+
+	length := full * chunkLength(size)
+	if remainder > 0 {
+		length += chunkLength(remainder)
+	}
+
+	return length + chunkLength(0)
+}
+
+// chunkLength - hex(size);chunk-signature=<64>\r\n<data>\r\n
+func chunkLength(size int64) int64 {
+	return int64(len(strconv.FormatInt(size, 16))) + int64(len(";chunk-signature=")) + 64 + 2 + size + 2
+}
+
+// chunkSigner - encodes a body with aws-chunked encoding, signing every chunk with the previous signature
+type chunkSigner struct {
+	body          io.Reader
+	chunk         []byte
+	key           []byte
+	now           time.Time
+	scope         string
+	prevSignature string
+	pending       bytes.Buffer
+	done          bool
+}
+
+func newChunkSigner(body io.Reader, chunkSize int, key []byte, now time.Time, scope string, seedSignature string) *chunkSigner {
+	return &chunkSigner{
+		body:          body,
+		chunk:         make([]byte, chunkSize),
+		key:           key,
+		now:           now,
+		scope:         scope,
+		prevSignature: seedSignature,
+	}
+}
+
+func (c *chunkSigner) Read(p []byte) (int, error) {
+	for c.pending.Len() == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(c.body, c.chunk)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+
+		if n > 0 {
+			c.writeChunk(c.chunk[:n])
+		}
+
+		if n == 0 || err != nil {
+			c.writeChunk(nil)
+			c.done = true
+		}
+	}
+
+	return c.pending.Read(p)
+}
+
+// writeChunk - sign and encode a single chunk
+func (c *chunkSigner) writeChunk(data []byte) {
+	toSign := strings.Join([]string{
+		sigV4ChunkAlgorithm,
+		c.now.Format(sigV4TimeFormat),
+		c.scope,
+		c.prevSignature,
+		sigV4EmptyPayloadHash,
+		sha256Hex(data),
+	}, "\n")
+
+	c.prevSignature = hmacHex(c.key, toSign)
+
+	fmt.Fprintf(&c.pending, "%x;chunk-signature=%s\r\n", len(data), c.prevSignature)
+	c.pending.Write(data)
+	c.pending.WriteString("\r\n")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hmacHex(key []byte, data string) string {
+	return hex.EncodeToString(hmacSHA256(key, data))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,244 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEventLineSize - longest line accepted in an event stream
+const maxEventLineSize = 1 << 20
+
+// ErrNotEventStream - the response is not a text/event-stream
+var ErrNotEventStream = errors.New("response is not an event stream")
+
+// Event - a server-sent event
+type Event struct {
+	// Last event ID, carried over from previous events when the event has no id field
+	ID string
+	// Event type, "message" when the event has no event field
+	Type string
+	// Data lines joined by a newline
+	Data string
+	// Reconnection time sent with the event, zero when the event has no retry field
+	Retry time.Duration
+}
+
+// Subscribe - subscribe to a text/event-stream, yielding events as they arrive.
+// When the stream ends or drops the client reconnects with Last-Event-ID, waiting for the server's retry field or the next retry strategy backoff.
+// Consecutive failed reconnects are bounded by the retry strategy, a connection delivering events resets the count. Without a retry strategy the client does not reconnect.
+// Iteration stops after yielding an error, 4XX responses, 204 No Content and non event stream responses are not reconnected.
+//
+// Example:
+//
+//	for event, err := range client.Subscribe(ctx, url, nil) {
+//		if err != nil {
+//			// Handle error
+//			break
+//		}
+//		fmt.Println(event.Type, event.Data)
+//	}
+func (a *Client) Subscribe(ctx context.Context, url string, headers map[string]string) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		stream := &eventStream{}
+		failures := 0
+
+		for {
+			received, err := a.consume(ctx, url, headers, stream, yield)
+			if errors.Is(err, errStopStream) {
+				return
+			}
+
+			if ctx.Err() != nil {
+				yield(Event{}, context.Cause(ctx))
+				return
+			}
+
+			if err != nil && !isReconnectable(err) {
+				yield(Event{}, err)
+				return
+			}
+
+			if received {
+				failures = 0
+			}
+
+			if failures >= len(a.RetryStrategy) {
+				if err != nil {
+					yield(Event{}, err)
+				}
+				return
+			}
+
+			delay := a.RetryStrategy[failures]
+			if stream.retry > 0 {
+				delay = stream.retry
+			}
+			failures++
+
+			if err = wait(ctx, delay); err != nil {
+				yield(Event{}, context.Cause(ctx))
+				return
+			}
+		}
+	}
+}
+
+// errStopStream - the consumer stopped iterating or the server asked the client not to reconnect
+var errStopStream = errors.New("stop event stream")
+
+// consume - connect and yield events until the stream ends, returns whether any event was received
+func (a *Client) consume(ctx context.Context, url string, headers map[string]string, stream *eventStream, yield func(Event, error) bool) (bool, error) {
+	streamHeaders := map[string]string{
+		"Accept":        "text/event-stream",
+		"Cache-Control": "no-cache",
+	}
+	if stream.lastEventID != "" {
+		streamHeaders["Last-Event-ID"] = stream.lastEventID
+	}
+
+	resp, err := a.sendOnce(ctx, &Request{Method: http.MethodGet, URL: url, Headers: mergeHeaders(headers, streamHeaders), stream: true})
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			discard(resp)
+		}
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return false, errStopStream
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		return false, fmt.Errorf("%w: %s", ErrNotEventStream, resp.Header.Get("Content-Type"))
+	}
+
+	return stream.read(resp.Body, yield)
+}
+
+// isReconnectable - dropped connections and recoverable API errors are reconnected
+func isReconnectable(err error) bool {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return isRecoverable(err)
+	}
+
+	return !errors.Is(err, ErrNotEventStream) && !errors.Is(err, ErrTimeout)
+}
+
+// eventStream - event stream parser state kept across reconnects
+type eventStream struct {
+	lastEventID string
+	retry       time.Duration
+}
+
+// read - parse events from the body, yielding each dispatched event
+func (s *eventStream) read(body io.Reader, yield func(Event, error) bool) (bool, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventLineSize)
+	scanner.Split(eventLineSplitter())
+
+	received := false
+	first := true
+	var eventType string
+	var retry time.Duration
+	var data strings.Builder
+	hasData := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		if line == "" {
+			if hasData {
+				event := Event{ID: s.lastEventID, Type: eventType, Data: data.String(), Retry: retry}
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				received = true
+				if !yield(event, nil) {
+					return received, errStopStream
+				}
+			}
+
+			eventType, retry = "", 0
+			data.Reset()
+			hasData = false
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				s.retry = retry
+			}
+		}
+	}
+
+	return received, scanner.Err()
+}
+
+// eventLineSplitter - split lines ending in CRLF, LF or CR. Lines ending in CR are returned straight away, a following LF is skipped
+func eventLineSplitter() bufio.SplitFunc {
+	skipLF := false
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		skipped := 0
+		if skipLF && len(data) > 0 {
+			skipLF = false
+			if data[0] == '\n' {
+				data, skipped = data[1:], 1
+			}
+		}
+
+		if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+			skipLF = data[i] == '\r'
+			return skipped + i + 1, data[:i], nil
+		}
+
+		if atEOF {
+			// incomplete line at the end of the stream
+			return skipped + len(data), nil, nil
+		}
+
+		return skipped, nil, nil
+	}
+}
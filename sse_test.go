@@ -0,0 +1,247 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+// collectEvents - parse a raw stream, returning the dispatched events
+func collectEvents(t *testing.T, raw string) []Event {
+	t.Helper()
+
+	var events []Event
+	_, err := (&eventStream{}).read(strings.NewReader(raw), func(event Event, _ error) bool {
+		events = append(events, event)
+		return true
+	})
+	odize.AssertNoError(t, err)
+	return events
+}
+
+func TestEventStream_read(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	err := group.
+		Test("should parse fields", func(t *testing.T) {
+			events := collectEvents(t, "id: 1\nevent: update\ndata: hello\nretry: 250\n\n")
+			odize.AssertEqual(t, []Event{{ID: "1", Type: "update", Data: "hello", Retry: 250 * time.Millisecond}}, events)
+		}).
+		Test("should join data lines and default the type", func(t *testing.T) {
+			events := collectEvents(t, "data: first\ndata:second\ndata\n\n")
+			odize.AssertEqual(t, []Event{{Type: "message", Data: "first\nsecond\n"}}, events)
+		}).
+		Test("should carry the last event id over", func(t *testing.T) {
+			events := collectEvents(t, "id: 7\ndata: a\n\ndata: b\n\n")
+			odize.AssertEqual(t, 2, len(events))
+			odize.AssertEqual(t, "7", events[1].ID)
+		}).
+		Test("should split CRLF and CR line endings", func(t *testing.T) {
+			events := collectEvents(t, "data: a\r\n\r\ndata: b\r\rdata: c\n\n")
+			odize.AssertEqual(t, []string{"a", "b", "c"}, []string{events[0].Data, events[1].Data, events[2].Data})
+		}).
+		Test("should ignore comments, empty events, invalid retry and the BOM", func(t *testing.T) {
+			events := collectEvents(t, "\ufeff: keep alive\n\nevent: empty\n\nretry: soon\ndata: x\n\n")
+			odize.AssertEqual(t, []Event{{Type: "message", Data: "x"}}, events)
+		}).
+		Test("should discard an incomplete event at the end of the stream", func(t *testing.T) {
+			events := collectEvents(t, "data: done\n\ndata: partial")
+			odize.AssertEqual(t, 1, len(events))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+// eventStreamServer - test server replying with an event stream
+func eventStreamServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, count int32)) (string, func() int32) {
+	srv, count := cacheTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		handler(w, r, n)
+	})
+
+	return srv.URL, count.Load
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var client *Client
+
+	group.BeforeEach(func() {
+		client = New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond})))
+	})
+
+	err := group.
+		Test("should reconnect with the last event id", func(t *testing.T) {
+			var lastEventIDs []string
+			url, _ := eventStreamServer(t, func(w http.ResponseWriter, r *http.Request, count int32) {
+				lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+				switch count {
+				case 1:
+					_, _ = w.Write([]byte("id: 1\ndata: a\n\nid: 2\ndata: b\n\n"))
+				case 2:
+					_, _ = w.Write([]byte("id: 3\ndata: c\n\n"))
+				default:
+					w.WriteHeader(http.StatusNoContent)
+				}
+			})
+
+			var data []string
+			for event, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertNoError(t, err)
+				data = append(data, event.Data)
+			}
+
+			odize.AssertEqual(t, []string{"a", "b", "c"}, data)
+			odize.AssertEqual(t, []string{"", "2", "3"}, lastEventIDs)
+		}).
+		Test("should wait for the server retry field", func(t *testing.T) {
+			url, _ := eventStreamServer(t, func(w http.ResponseWriter, _ *http.Request, count int32) {
+				if count == 1 {
+					_, _ = w.Write([]byte("retry: 100\ndata: a\n\n"))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			start := time.Now()
+			for _, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertNoError(t, err)
+			}
+			odize.AssertTrue(t, time.Since(start) >= 100*time.Millisecond)
+		}).
+		Test("should stop reconnecting once the retry strategy is exhausted", func(t *testing.T) {
+			url, count := eventStreamServer(t, func(_ http.ResponseWriter, _ *http.Request, _ int32) {})
+
+			events := 0
+			for _, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertNoError(t, err)
+				events++
+			}
+			odize.AssertEqual(t, 0, events)
+			odize.AssertEqual(t, int32(3), count())
+		}).
+		Test("default client timeout should not cut off the stream", func(t *testing.T) {
+			url, count := eventStreamServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				flusher, ok := w.(http.Flusher)
+				odize.AssertTrue(t, ok)
+				for _, data := range []string{"a", "b", "c"} {
+					_, _ = w.Write([]byte("data: " + data + "\n\n"))
+					flusher.Flush()
+					time.Sleep(80 * time.Millisecond)
+				}
+			})
+
+			client = New(&Options{})
+			httpClient, ok := client.Client.(*http.Client)
+			odize.AssertTrue(t, ok)
+			httpClient.Timeout = 100 * time.Millisecond
+
+			var data []string
+			for event, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertNoError(t, err)
+				data = append(data, event.Data)
+			}
+
+			odize.AssertEqual(t, []string{"a", "b", "c"}, data)
+			odize.AssertEqual(t, int32(1), count())
+		}).
+		Test("default client timeout should bound the wait for the response headers", func(t *testing.T) {
+			url, _ := eventStreamServer(t, func(_ http.ResponseWriter, r *http.Request, _ int32) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			})
+
+			client = New(&Options{})
+			httpClient, ok := client.Client.(*http.Client)
+			odize.AssertTrue(t, ok)
+			httpClient.Timeout = 50 * time.Millisecond
+
+			var errs []error
+			for _, err := range client.Subscribe(context.Background(), url, nil) {
+				errs = append(errs, err)
+			}
+
+			odize.AssertEqual(t, 1, len(errs))
+			odize.AssertTrue(t, errors.Is(errs[0], ErrAttemptTimeout))
+		}).
+		Test("server errors should make one attempt per reconnect", func(t *testing.T) {
+			url, count := eventStreamServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+
+			client = New(WithOpts(WithRetryStrategy(&[]time.Duration{time.Millisecond, time.Millisecond, time.Millisecond})))
+			var apiErr *APIError
+			for _, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertTrue(t, errors.As(err, &apiErr))
+			}
+			odize.AssertEqual(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+			odize.AssertEqual(t, int32(4), count())
+		}).
+		Test("should not reconnect on a client error", func(t *testing.T) {
+			url, count := eventStreamServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+
+			var apiErr *APIError
+			for _, err := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertTrue(t, errors.As(err, &apiErr))
+			}
+			odize.AssertEqual(t, http.StatusUnauthorized, apiErr.StatusCode)
+			odize.AssertEqual(t, int32(1), count())
+		}).
+		Test("should fail when the response is not an event stream", func(t *testing.T) {
+			srv, _ := cacheTestServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte("{}"))
+			})
+
+			var got error
+			for _, err := range client.Subscribe(context.Background(), srv.URL, nil) {
+				got = err
+			}
+			odize.AssertTrue(t, errors.Is(got, ErrNotEventStream))
+		}).
+		Test("breaking out of the loop should stop the subscription", func(t *testing.T) {
+			url, count := eventStreamServer(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+				_, _ = w.Write([]byte("data: a\n\ndata: b\n\n"))
+			})
+
+			for event := range client.Subscribe(context.Background(), url, nil) {
+				odize.AssertEqual(t, "a", event.Data)
+				break
+			}
+			odize.AssertEqual(t, int32(1), count())
+		}).
+		Test("cancelled context should end the subscription with an error", func(t *testing.T) {
+			url, _ := eventStreamServer(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				_, _ = w.Write([]byte("data: a\n\n"))
+				flusher, ok := w.(http.Flusher)
+				odize.AssertTrue(t, ok)
+				flusher.Flush()
+				<-r.Context().Done()
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var got error
+			for event, err := range client.Subscribe(ctx, url, nil) {
+				if err != nil {
+					got = err
+					continue
+				}
+				odize.AssertEqual(t, "a", event.Data)
+				cancel()
+			}
+			odize.AssertTrue(t, errors.Is(got, context.Canceled))
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
@@ -0,0 +1,262 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCertificatePinMismatch = errors.New("tls: no peer certificate matches the pinned public keys")
+	ErrNoCertificatesInPEM    = errors.New("tls: no certificates found in PEM")
+	ErrInvalidSPKIHash        = errors.New("tls: invalid SPKI hash, expected a base64 encoded SHA-256 hash")
+)
+
+// TLSOptions - TLS configuration applied to the default transport.
+// Ignored when a custom HTTP client is provided.
+type TLSOptions struct {
+	// PEM encoded client certificate and key files used for mutual TLS
+	CertFile string
+	KeyFile  string
+	// Reload CertFile / KeyFile when they change on disk, default is false
+	ReloadCertificates bool
+	// In memory client certificate used for mutual TLS, ignored when CertFile is set
+	Certificate *tls.Certificate
+	// Root CAs used to verify the server, default is the system pool
+	RootCAs *x509.CertPool
+	// Minimum TLS version, default is TLS 1.2
+	MinVersion uint16
+	// Override the server name used for SNI and certificate verification
+	ServerName string
+	// Base64 encoded SHA-256 hashes of the server's SubjectPublicKeyInfo, at least one certificate in the chain must match
+	PinnedSPKIHashes []string
+}
+
+// WithClientCertificate - use the PEM encoded certificate and key files for mutual TLS
+func WithClientCertificate(certFile string, keyFile string) FnOpts {
+	return func(o *Options) error {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return err
+		}
+
+		opts := tlsOptions(o)
+		opts.CertFile = certFile
+		opts.KeyFile = keyFile
+		return nil
+	}
+}
+
+// WithClientCertificatePEM - use the in memory PEM encoded certificate and key for mutual TLS
+func WithClientCertificatePEM(certPEM []byte, keyPEM []byte) FnOpts {
+	return func(o *Options) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return err
+		}
+
+		tlsOptions(o).Certificate = &cert
+		return nil
+	}
+}
+
+// WithCertificateReload - reload the client certificate files when they are rotated on disk
+func WithCertificateReload() FnOpts {
+	return func(o *Options) error {
+		tlsOptions(o).ReloadCertificates = true
+		return nil
+	}
+}
+
+// WithRootCAs - verify the server with a custom root CA pool
+func WithRootCAs(pool *x509.CertPool) FnOpts {
+	return func(o *Options) error {
+		tlsOptions(o).RootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAPEM - add PEM encoded CA certificates to the root CA pool
+func WithRootCAPEM(caPEM []byte) FnOpts {
+	return func(o *Options) error {
+		opts := tlsOptions(o)
+		if opts.RootCAs == nil {
+			opts.RootCAs = x509.NewCertPool()
+		}
+
+		if !opts.RootCAs.AppendCertsFromPEM(caPEM) {
+			return ErrNoCertificatesInPEM
+		}
+		return nil
+	}
+}
+
+// WithRootCAFile - add a PEM encoded CA file to the root CA pool
+func WithRootCAFile(path string) FnOpts {
+	return func(o *Options) error {
+		caPEM, err := os.ReadFile(path) // #nosec G304 -- path is provided by the caller
+		if err != nil {
+			return err
+		}
+
+		return WithRootCAPEM(caPEM)(o)
+	}
+}
+
+// WithMinTLSVersion - set the minimum TLS version, e.g. tls.VersionTLS13
+func WithMinTLSVersion(version uint16) FnOpts {
+	return func(o *Options) error {
+		tlsOptions(o).MinVersion = version
+		return nil
+	}
+}
+
+// WithServerName - override the server name used for SNI and certificate verification
+func WithServerName(name string) FnOpts {
+	return func(o *Options) error {
+		tlsOptions(o).ServerName = name
+		return nil
+	}
+}
+
+// WithPinnedSPKI - pin the server's public key by base64 encoded SHA-256 SubjectPublicKeyInfo hash
+func WithPinnedSPKI(hashes ...string) FnOpts {
+	return func(o *Options) error {
+		for _, hash := range hashes {
+			decoded, err := base64.StdEncoding.DecodeString(hash)
+			if err != nil || len(decoded) != sha256.Size {
+				return ErrInvalidSPKIHash
+			}
+		}
+
+		opts := tlsOptions(o)
+		opts.PinnedSPKIHashes = append(opts.PinnedSPKIHashes, hashes...)
+		return nil
+	}
+}
+
+// SPKIHash - returns the base64 encoded SHA-256 hash of the certificate's SubjectPublicKeyInfo, for use with WithPinnedSPKI
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// tlsOptions - returns the TLS options, initialising them if needed
+func tlsOptions(o *Options) *TLSOptions {
+	if o.TLS == nil {
+		o.TLS = &TLSOptions{}
+	}
+
+	return o.TLS
+}
+
+// config - build the tls config
+func (t *TLSOptions) config() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    t.RootCAs,
+		ServerName: t.ServerName,
+	}
+
+	if t.MinVersion != 0 {
+		cfg.MinVersion = t.MinVersion
+	}
+
+	switch {
+	case t.CertFile != "":
+		reloader := &certReloader{certFile: t.CertFile, keyFile: t.KeyFile, watch: t.ReloadCertificates}
+		cfg.GetClientCertificate = reloader.getClientCertificate
+	case t.Certificate != nil:
+		cfg.Certificates = []tls.Certificate{*t.Certificate}
+	}
+
+	if len(t.PinnedSPKIHashes) > 0 {
+		pins := map[string]bool{}
+		for _, hash := range t.PinnedSPKIHashes {
+			pins[hash] = true
+		}
+		cfg.VerifyConnection = verifyPins(pins)
+	}
+
+	return cfg
+}
+
+// verifyPins - check at least one certificate presented by the server matches a pin
+func verifyPins(pins map[string]bool) func(cs tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		for _, cert := range cs.PeerCertificates {
+			if pins[SPKIHash(cert)] {
+				return nil
+			}
+		}
+
+		return ErrCertificatePinMismatch
+	}
+}
+
+// certReloader - loads a client certificate from disk, reloading it when the files change
+type certReloader struct {
+	certFile string
+	keyFile  string
+	watch    bool
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+func (c *certReloader) getClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cert != nil && !c.watch {
+		return c.cert, nil
+	}
+
+	certTime, keyTime, err := c.modTimes()
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+
+	if c.cert != nil && certTime.Equal(c.certTime) && keyTime.Equal(c.keyTime) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		// a rotation may be half written, keep serving the last good certificate
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+
+	c.cert = &cert
+	c.certTime = certTime
+	c.keyTime = keyTime
+
+	return c.cert, nil
+}
+
+// modTimes - modification times of the certificate and key files
+func (c *certReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,259 @@
+package fetch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (c testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	odize.AssertNoError(t, err)
+	return cert
+}
+
+// newTestCert - create a certificate signed by the parent, self signed when the parent is nil
+func newTestCert(t *testing.T, commonName string, parent *testCert, isCA bool) testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	odize.AssertNoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	odize.AssertNoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		DNSNames:              []string{commonName},
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	odize.AssertNoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	odize.AssertNoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	odize.AssertNoError(t, err)
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// newMTLSServer - TLS server requiring a client certificate signed by the CA, responds with the client's common name
+func newMTLSServer(t *testing.T, ca testCert, server testCert) *httptest.Server {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Client", r.TLS.PeerCertificates[0].Subject.CommonName)
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{server.tlsCertificate(t)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestTLS_mutual_tls(t *testing.T) {
+	group := odize.NewGroup(t, nil)
+
+	var ca, server, clientCert testCert
+	var srv *httptest.Server
+
+	group.BeforeAll(func() {
+		ca = newTestCert(t, "test-ca", nil, true)
+		server = newTestCert(t, "localhost", &ca, false)
+		clientCert = newTestCert(t, "client-a", &ca, false)
+		srv = newMTLSServer(t, ca, server)
+	})
+
+	err := group.
+		Test("should connect with in memory certificate", func(t *testing.T) {
+			c := New(WithOpts(
+				WithClientCertificatePEM(clientCert.certPEM, clientCert.keyPEM),
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+			))
+
+			resp, err := c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+			odize.AssertEqual(t, "client-a", resp.Header.Get("X-Client"))
+		}).
+		Test("should connect with certificate files", func(t *testing.T) {
+			dir := t.TempDir()
+			certFile, keyFile := writeCertFiles(t, dir, clientCert)
+			caFile := filepath.Join(dir, "ca.pem")
+			odize.AssertNoError(t, os.WriteFile(caFile, ca.certPEM, 0o600))
+
+			c := New(WithOpts(
+				WithClientCertificate(certFile, keyFile),
+				WithRootCAFile(caFile),
+				WithServerName("localhost"),
+			))
+
+			resp, err := c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			defer resp.Body.Close()
+			odize.AssertEqual(t, "client-a", resp.Header.Get("X-Client"))
+		}).
+		Test("should fail without client certificate", func(t *testing.T) {
+			c := New(WithOpts(
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+			))
+
+			_, err := c.Get(srv.URL, nil)
+			odize.AssertError(t, err)
+		}).
+		Test("should reload rotated certificate files", func(t *testing.T) {
+			dir := t.TempDir()
+			certFile, keyFile := writeCertFiles(t, dir, clientCert)
+
+			c := New(WithOpts(
+				WithClientCertificate(certFile, keyFile),
+				WithCertificateReload(),
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+			))
+
+			resp, err := c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			resp.Body.Close()
+			odize.AssertEqual(t, "client-a", resp.Header.Get("X-Client"))
+
+			rotated := newTestCert(t, "client-b", &ca, false)
+			writeCertFiles(t, dir, rotated)
+			later := time.Now().Add(time.Minute)
+			odize.AssertNoError(t, os.Chtimes(certFile, later, later))
+			odize.AssertNoError(t, os.Chtimes(keyFile, later, later))
+
+			httpClient, ok := c.Client.(*http.Client)
+			odize.AssertTrue(t, ok)
+			httpClient.CloseIdleConnections()
+
+			resp, err = c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			resp.Body.Close()
+			odize.AssertEqual(t, "client-b", resp.Header.Get("X-Client"))
+		}).
+		Test("should accept matching pinned key", func(t *testing.T) {
+			c := New(WithOpts(
+				WithClientCertificatePEM(clientCert.certPEM, clientCert.keyPEM),
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+				WithPinnedSPKI(SPKIHash(server.cert)),
+			))
+
+			resp, err := c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			resp.Body.Close()
+		}).
+		Test("should reject mismatched pinned key", func(t *testing.T) {
+			other := newTestCert(t, "other", nil, false)
+			c := New(WithOpts(
+				WithClientCertificatePEM(clientCert.certPEM, clientCert.keyPEM),
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+				WithPinnedSPKI(SPKIHash(other.cert)),
+			))
+
+			_, err := c.Get(srv.URL, nil)
+			odize.AssertTrue(t, errors.Is(err, ErrCertificatePinMismatch))
+		}).
+		Test("should negotiate the minimum version", func(t *testing.T) {
+			c := New(WithOpts(
+				WithClientCertificatePEM(clientCert.certPEM, clientCert.keyPEM),
+				WithRootCAPEM(ca.certPEM),
+				WithServerName("localhost"),
+				WithMinTLSVersion(tls.VersionTLS13),
+			))
+
+			resp, err := c.Get(srv.URL, nil)
+			odize.AssertNoError(t, err)
+			resp.Body.Close()
+			odize.AssertEqual(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+		}).
+		Run()
+	odize.AssertNoError(t, err)
+}
+
+func TestWithPinnedSPKI_invalid_hash(t *testing.T) {
+	options := Options{}
+	err := WithPinnedSPKI("not-a-hash")(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrInvalidSPKIHash))
+}
+
+func TestWithRootCAPEM_invalid_pem(t *testing.T) {
+	options := Options{}
+	err := WithRootCAPEM([]byte("nope"))(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrNoCertificatesInPEM))
+}
+
+func TestWithClientCertificate_missing_files(t *testing.T) {
+	options := Options{}
+	err := WithClientCertificate("missing.pem", "missing-key.pem")(&options)
+	odize.AssertError(t, err)
+	odize.AssertNil(t, options.TLS)
+}
+
+func TestNew_with_tls_options(t *testing.T) {
+	c := New(WithOpts(WithServerName("example.com"), WithMinTLSVersion(tls.VersionTLS13)))
+
+	httpClient, ok := c.Client.(*http.Client)
+	odize.AssertTrue(t, ok)
+	transport, ok := httpClient.Transport.(*http.Transport)
+	odize.AssertTrue(t, ok)
+	odize.AssertEqual(t, "example.com", transport.TLSClientConfig.ServerName)
+	odize.AssertEqual(t, uint16(tls.VersionTLS13), transport.TLSClientConfig.MinVersion)
+}
+
+func writeCertFiles(t *testing.T, dir string, cert testCert) (string, string) {
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	odize.AssertNoError(t, os.WriteFile(certFile, cert.certPEM, 0o600))
+	odize.AssertNoError(t, os.WriteFile(keyFile, cert.keyPEM, 0o600))
+	return certFile, keyFile
+}
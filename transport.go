@@ -0,0 +1,276 @@
+package fetch
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrInvalidTransportOption = errors.New("invalid transport option")
+)
+
+// TransportOptions - tuning applied on top of the default transport, zero values keep the defaults.
+// Ignored when a custom HTTP client is provided.
+type TransportOptions struct {
+	// Maximum time to establish a connection, default is 15s
+	DialTimeout time.Duration
+	// TCP keep-alive period, negative disables keep-alive probes, default is 15s
+	KeepAlive time.Duration
+	// Maximum time for the TLS handshake, default is 10s
+	TLSHandshakeTimeout time.Duration
+	// Maximum time to wait for response headers after the request is written, default is none
+	ResponseHeaderTimeout time.Duration
+	// Maximum time to wait for a 100-continue response, default is 1s
+	ExpectContinueTimeout time.Duration
+	// Maximum idle connections across all hosts, default is 10
+	MaxIdleConns int
+	// Maximum idle connections per host, default is 2
+	MaxIdleConnsPerHost int
+	// Maximum connections per host, including active connections, default is unlimited
+	MaxConnsPerHost int
+	// Maximum time an idle connection is kept in the pool, default is 30s
+	IdleConnTimeout time.Duration
+	// Maximum size of the response headers in bytes, default is 1MB
+	MaxResponseHeaderBytes int64
+	// Disable connection reuse
+	DisableKeepAlives bool
+	// Only use HTTP/1.1
+	DisableHTTP2 bool
+	// HTTP/2 settings, e.g. MaxConcurrentStreams, PingTimeout
+	HTTP2 *http.HTTP2Config
+	// Proxy selection, default is the environment (HTTP_PROXY, HTTPS_PROXY, NO_PROXY)
+	Proxy func(*http.Request) (*url.URL, error)
+	// Never use a proxy, overrides Proxy
+	DisableProxy bool
+}
+
+// WithDialTimeout - maximum time to establish a connection
+func WithDialTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		if timeout < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).DialTimeout = timeout
+		return nil
+	}
+}
+
+// WithKeepAlive - TCP keep-alive period, negative disables keep-alive probes
+func WithKeepAlive(keepAlive time.Duration) FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).KeepAlive = keepAlive
+		return nil
+	}
+}
+
+// WithTLSHandshakeTimeout - maximum time for the TLS handshake
+func WithTLSHandshakeTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		if timeout < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).TLSHandshakeTimeout = timeout
+		return nil
+	}
+}
+
+// WithResponseHeaderTimeout - maximum time to wait for response headers after the request is written
+func WithResponseHeaderTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		if timeout < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).ResponseHeaderTimeout = timeout
+		return nil
+	}
+}
+
+// WithExpectContinueTimeout - maximum time to wait for a 100-continue response
+func WithExpectContinueTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		if timeout < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).ExpectContinueTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxIdleConns - maximum idle connections across all hosts
+func WithMaxIdleConns(n int) FnOpts {
+	return func(o *Options) error {
+		if n < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).MaxIdleConns = n
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost - maximum idle connections per host
+func WithMaxIdleConnsPerHost(n int) FnOpts {
+	return func(o *Options) error {
+		if n < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).MaxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// WithMaxConnsPerHost - maximum connections per host, including active connections
+func WithMaxConnsPerHost(n int) FnOpts {
+	return func(o *Options) error {
+		if n < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).MaxConnsPerHost = n
+		return nil
+	}
+}
+
+// WithIdleConnTimeout - maximum time an idle connection is kept in the pool
+func WithIdleConnTimeout(timeout time.Duration) FnOpts {
+	return func(o *Options) error {
+		if timeout < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).IdleConnTimeout = timeout
+		return nil
+	}
+}
+
+// WithMaxResponseHeaderBytes - maximum size of the response headers in bytes
+func WithMaxResponseHeaderBytes(size int64) FnOpts {
+	return func(o *Options) error {
+		if size < 0 {
+			return ErrInvalidTransportOption
+		}
+		transportOptions(o).MaxResponseHeaderBytes = size
+		return nil
+	}
+}
+
+// WithDisableKeepAlives - disable connection reuse
+func WithDisableKeepAlives() FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).DisableKeepAlives = true
+		return nil
+	}
+}
+
+// WithDisableHTTP2 - only use HTTP/1.1
+func WithDisableHTTP2() FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).DisableHTTP2 = true
+		return nil
+	}
+}
+
+// WithHTTP2Config - set HTTP/2 settings
+func WithHTTP2Config(config *http.HTTP2Config) FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).HTTP2 = config
+		return nil
+	}
+}
+
+// WithProxy - send every request through the proxy URL
+func WithProxy(proxyURL string) FnOpts {
+	return func(o *Options) error {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+		transportOptions(o).Proxy = http.ProxyURL(parsed)
+		return nil
+	}
+}
+
+// WithProxyFunc - select a proxy per request
+func WithProxyFunc(proxy func(*http.Request) (*url.URL, error)) FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).Proxy = proxy
+		return nil
+	}
+}
+
+// WithoutProxy - never use a proxy, ignoring the environment
+func WithoutProxy() FnOpts {
+	return func(o *Options) error {
+		transportOptions(o).DisableProxy = true
+		return nil
+	}
+}
+
+// transportOptions - returns the transport options, initialising them if needed
+func transportOptions(o *Options) *TransportOptions {
+	if o.Transport == nil {
+		o.Transport = &TransportOptions{}
+	}
+
+	return o.Transport
+}
+
+// apply - apply the tuning to the transport
+func (t *TransportOptions) apply(transport *http.Transport) {
+	if t.DialTimeout != 0 || t.KeepAlive != 0 {
+		dialer := &net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 15 * time.Second,
+		}
+		if t.DialTimeout != 0 {
+			dialer.Timeout = t.DialTimeout
+		}
+		if t.KeepAlive != 0 {
+			dialer.KeepAlive = t.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	if t.TLSHandshakeTimeout != 0 {
+		transport.TLSHandshakeTimeout = t.TLSHandshakeTimeout
+	}
+	if t.ResponseHeaderTimeout != 0 {
+		transport.ResponseHeaderTimeout = t.ResponseHeaderTimeout
+	}
+	if t.ExpectContinueTimeout != 0 {
+		transport.ExpectContinueTimeout = t.ExpectContinueTimeout
+	}
+	if t.MaxIdleConns != 0 {
+		transport.MaxIdleConns = t.MaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost != 0 {
+		transport.MaxIdleConnsPerHost = t.MaxIdleConnsPerHost
+	}
+	if t.MaxConnsPerHost != 0 {
+		transport.MaxConnsPerHost = t.MaxConnsPerHost
+	}
+	if t.IdleConnTimeout != 0 {
+		transport.IdleConnTimeout = t.IdleConnTimeout
+	}
+	if t.MaxResponseHeaderBytes != 0 {
+		transport.MaxResponseHeaderBytes = t.MaxResponseHeaderBytes
+	}
+
+	transport.DisableKeepAlives = t.DisableKeepAlives
+
+	if t.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP1(true)
+	}
+	if t.HTTP2 != nil {
+		transport.HTTP2 = t.HTTP2
+	}
+
+	if t.Proxy != nil {
+		transport.Proxy = t.Proxy
+	}
+	if t.DisableProxy {
+		transport.Proxy = nil
+	}
+}
@@ -0,0 +1,120 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-gorilla-au/odize"
+)
+
+func defaultTransportOf(t *testing.T, c *Client) *http.Transport {
+	httpClient, ok := c.Client.(*http.Client)
+	odize.AssertTrue(t, ok)
+	transport, ok := httpClient.Transport.(*http.Transport)
+	odize.AssertTrue(t, ok)
+	return transport
+}
+
+func TestNew_with_transport_options(t *testing.T) {
+	c := New(WithOpts(
+		WithTLSHandshakeTimeout(2*time.Second),
+		WithResponseHeaderTimeout(3*time.Second),
+		WithExpectContinueTimeout(4*time.Second),
+		WithMaxIdleConns(100),
+		WithMaxIdleConnsPerHost(50),
+		WithMaxConnsPerHost(200),
+		WithIdleConnTimeout(90*time.Second),
+	))
+
+	transport := defaultTransportOf(t, c)
+	odize.AssertEqual(t, 2*time.Second, transport.TLSHandshakeTimeout)
+	odize.AssertEqual(t, 3*time.Second, transport.ResponseHeaderTimeout)
+	odize.AssertEqual(t, 4*time.Second, transport.ExpectContinueTimeout)
+	odize.AssertEqual(t, 100, transport.MaxIdleConns)
+	odize.AssertEqual(t, 50, transport.MaxIdleConnsPerHost)
+	odize.AssertEqual(t, 200, transport.MaxConnsPerHost)
+	odize.AssertEqual(t, 90*time.Second, transport.IdleConnTimeout)
+	odize.AssertTrue(t, transport.ForceAttemptHTTP2)
+}
+
+func TestNew_transport_options_keep_defaults(t *testing.T) {
+	c := New(WithOpts(WithMaxIdleConnsPerHost(50)))
+
+	transport := defaultTransportOf(t, c)
+	expected := setDefaultTransport()
+	odize.AssertEqual(t, 50, transport.MaxIdleConnsPerHost)
+	odize.AssertEqual(t, expected.MaxIdleConns, transport.MaxIdleConns)
+	odize.AssertEqual(t, expected.IdleConnTimeout, transport.IdleConnTimeout)
+	odize.AssertEqual(t, expected.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	odize.AssertTrue(t, transport.Proxy != nil)
+}
+
+func TestNew_with_max_response_header_bytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Large", strings.Repeat("x", 4096))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(WithOpts(WithMaxResponseHeaderBytes(1024)))
+	odize.AssertEqual(t, int64(1024), defaultTransportOf(t, c).MaxResponseHeaderBytes)
+
+	_, err := c.Get(srv.URL, nil)
+	odize.AssertError(t, err)
+}
+
+func TestNew_with_disable_http2(t *testing.T) {
+	c := New(WithOpts(WithDisableHTTP2(), WithDisableKeepAlives()))
+
+	transport := defaultTransportOf(t, c)
+	odize.AssertFalse(t, transport.ForceAttemptHTTP2)
+	odize.AssertTrue(t, transport.Protocols.HTTP1())
+	odize.AssertFalse(t, transport.Protocols.HTTP2())
+	odize.AssertTrue(t, transport.DisableKeepAlives)
+}
+
+func TestNew_with_http2_config(t *testing.T) {
+	config := &http.HTTP2Config{MaxConcurrentStreams: 500, PingTimeout: 5 * time.Second}
+	c := New(WithOpts(WithHTTP2Config(config)))
+
+	odize.AssertEqual(t, config, defaultTransportOf(t, c).HTTP2)
+}
+
+func TestNew_without_proxy(t *testing.T) {
+	c := New(WithOpts(WithoutProxy()))
+
+	odize.AssertTrue(t, defaultTransportOf(t, c).Proxy == nil)
+}
+
+func TestNew_with_proxy_should_route_through_proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	c := New(WithOpts(WithProxy(proxy.URL), WithDialTimeout(time.Second), WithKeepAlive(-1)))
+
+	resp, err := c.Get("http://upstream.invalid/resource", nil)
+	odize.AssertNoError(t, err)
+	defer resp.Body.Close()
+	odize.AssertEqual(t, "http://upstream.invalid/resource", proxied)
+}
+
+func TestWithMaxIdleConns_negative(t *testing.T) {
+	options := Options{}
+	err := WithMaxIdleConns(-1)(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrInvalidTransportOption))
+	odize.AssertNil(t, options.Transport)
+}
+
+func TestWithDialTimeout_negative(t *testing.T) {
+	options := Options{}
+	err := WithDialTimeout(-time.Second)(&options)
+	odize.AssertTrue(t, errors.Is(err, ErrInvalidTransportOption))
+}